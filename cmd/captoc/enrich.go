@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"captoc/internal/config"
+	"captoc/internal/dictionary"
+	"captoc/internal/enrich"
+	"captoc/internal/parser"
+)
+
+// enrichData fills empty reading, meaning and Hán Việt cells of vocabulary
+// files from the configured local dictionaries
+func enrichData(args []string) {
+	flags := flag.NewFlagSet("enrich", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print a diff of the files that would change without writing them")
+	contentType := flags.String("type", "tuvung", "content type whose files are enriched")
+	flags.Usage = func() {
+		fmt.Println("Usage: captoc enrich [-dry-run] [-type tuvung] [files...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	cfg, err := config.Load("config.yaml")
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	dictionaries := cfg.Dictionaries
+	if dictionaries.JMdict == "" && dictionaries.KANJIDIC == "" && dictionaries.HanViet == "" {
+		fmt.Println("Error: no dictionaries configured (dictionaries.jmdict, kanjidic or hanviet)")
+		os.Exit(1)
+	}
+
+	fmt.Println("Loading dictionaries...")
+	dicts, err := dictionary.Load(dictionaries)
+	if err != nil {
+		fmt.Printf("Error loading dictionaries: %v\n", err)
+		os.Exit(1)
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		if files, err = parser.ContentFiles(filepath.Join(cfg.DataDir, *contentType)); err != nil {
+			fmt.Printf("Error reading data files: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fields := enrich.FieldsFor(cfg.ContentTypes[*contentType])
+	total := 0
+	for _, file := range files {
+		changes, diff, err := enrich.File(file, fields, dicts, *dryRun)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		if *dryRun {
+			fmt.Print(diff)
+		} else {
+			fmt.Printf("%s\n", file)
+			for _, change := range changes {
+				fmt.Printf("  row %d %s: %s = %q\n", change.Row, change.Word, change.Field, change.Value)
+			}
+		}
+		total += len(changes)
+	}
+
+	if *dryRun {
+		fmt.Printf("Dry run: %d cells would be filled.\n", total)
+	} else {
+		fmt.Printf("Filled %d cells.\n", total)
+	}
+}
@@ -3,10 +3,20 @@ package main
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
+	"captoc/internal/collection"
 	"captoc/internal/config"
+	"captoc/internal/dictionary"
+	"captoc/internal/kanji"
+	"captoc/internal/media"
 	"captoc/internal/parser"
+	"captoc/internal/quiz"
+	"captoc/internal/search"
+	"captoc/internal/study"
+	"captoc/internal/taxonomy"
 	"captoc/internal/template"
 )
 
@@ -25,6 +35,8 @@ func main() {
 		preview()
 	case "clean":
 		clean()
+	case "enrich":
+		enrichData(os.Args[2:])
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -38,6 +50,7 @@ func printUsage() {
 	fmt.Println("  build    Generate static website from data files")
 	fmt.Println("  preview  Start a local server to preview the website")
 	fmt.Println("  clean    Remove generated output files")
+	fmt.Println("  enrich   Fill empty vocabulary cells from local dictionaries")
 }
 
 func build() {
@@ -70,20 +83,128 @@ func build() {
 
 	// Process data files
 	fmt.Println("Processing data files...")
-	if err := processDataFiles(cfg); err != nil {
+	contents, err := processDataFiles(cfg)
+	if err != nil {
 		fmt.Printf("Error processing data files: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Println("Data files processed.")
 
+	// Generate content types derived from other content
+	fmt.Println("Generating derived content...")
+	derived, err := generateDerivedContent(cfg, contents)
+	if err != nil {
+		fmt.Printf("Error generating derived content: %v\n", err)
+		os.Exit(1)
+	}
+	contents = append(contents, derived...)
+
+	// Generate pages gathering rows from across the data
+	fmt.Println("Generating collections...")
+	collections, err := generateCollections(cfg, contents)
+	if err != nil {
+		fmt.Printf("Error generating collections: %v\n", err)
+		os.Exit(1)
+	}
+	contents = append(contents, collections...)
+
+	// Build the navigation of the pages, checking the references of the
+	// menu, before rendering any page with it
+	nav, err := template.BuildNav(cfg, contents)
+	if err != nil {
+		fmt.Printf("Error building navigation: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Stop before writing any page when two pages would share a file
+	if _, err := pagePaths(cfg, nav, contents); err != nil {
+		fmt.Printf("Error checking page paths: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Render the pages of the data files, derived files and collections
+	fmt.Println("Rendering content pages...")
+	if err := renderContentPages(cfg, nav, contents); err != nil {
+		fmt.Printf("Error rendering content pages: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Generate the listing pages of the content types and their sections
+	fmt.Println("Generating listing pages...")
+	if err := generateSectionPages(cfg, nav, contents); err != nil {
+		fmt.Printf("Error generating listing pages: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Copy audio files referenced by the data
+	fmt.Println("Copying media files...")
+	if err := copyMedia(cfg, contents); err != nil {
+		fmt.Printf("Error copying media files: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Generate exam pages
+	fmt.Println("Generating exam pages...")
+	if err := generateExamPages(cfg, nav, contents); err != nil {
+		fmt.Printf("Error generating exam pages: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Warn about references to pages or rows that do not exist
+	checkReferences(cfg, contents)
+
+	// Generate shuffled test forms
+	fmt.Println("Generating test variants...")
+	if err := generateVariantPages(cfg, nav, contents); err != nil {
+		fmt.Printf("Error generating test variants: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Generate study decks and pages
+	fmt.Println("Generating study pages...")
+	if err := generateStudyPages(cfg, nav, contents); err != nil {
+		fmt.Printf("Error generating study pages: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Generate kanji pages
+	if cfg.Kanji.Enabled {
+		fmt.Println("Generating kanji pages...")
+		if err := generateKanjiPages(cfg, nav, contents); err != nil {
+			fmt.Printf("Error generating kanji pages: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Generate tag pages
+	fmt.Println("Generating tag pages...")
+	if err := generateTagPages(cfg, nav, contents); err != nil {
+		fmt.Printf("Error generating tag pages: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Generate search index and page
+	fmt.Println("Generating search index...")
+	if err := generateSearch(cfg, nav, contents); err != nil {
+		fmt.Printf("Error generating search index: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Generate index page
 	fmt.Println("Generating index page...")
-	if err := generateIndexPage(cfg); err != nil {
+	if err := generateIndexPage(cfg, nav); err != nil {
 		fmt.Printf("Error generating index page: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Redirect old paths to the pages now at other paths
+	fmt.Println("Generating redirects...")
+	if err := generateRedirects(cfg, nav, contents); err != nil {
+		fmt.Printf("Error generating redirects: %v\n", err)
+		os.Exit(1)
+	}
+
 	fmt.Println("Build completed successfully!")
 }
 
@@ -198,11 +319,15 @@ func copyStaticAssets(cfg *config.Config) error {
 	return nil
 }
 
-func processDataFiles(cfg *config.Config) error {
+// processDataFiles parses every data file, returning the parsed content for
+// the build stages that work across all files
+func processDataFiles(cfg *config.Config) ([]*parser.ContentData, error) {
+	var contents []*parser.ContentData
+
 	// Process all data directories
 	dataDirs, err := os.ReadDir(cfg.DataDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, dir := range dataDirs {
@@ -218,61 +343,522 @@ func processDataFiles(cfg *config.Config) error {
 			fmt.Printf("Warning: No configuration found for content type '%s', using default template\n", contentType)
 		}
 
+		// CSV and JSON files, including those in section subdirectories;
+		// passage sidecars are loaded with the file they belong to
 		dirPath := filepath.Join(cfg.DataDir, contentType)
-		files, err := os.ReadDir(dirPath)
+		files, err := parser.ContentFiles(dirPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		for _, file := range files {
-			if file.IsDir() {
+		for _, filePath := range files {
+			// Index pages of content types and sections use the name
+			if filepath.Base(parser.ContentIDOf(dirPath, filePath)) == parser.IndexID {
+				fmt.Printf("Warning: Skipping %s: the name index is reserved for index pages\n", filePath)
 				continue
 			}
 
-			// Support both CSV and JSON files
-			ext := filepath.Ext(file.Name())
-			if ext != ".csv" && ext != ".json" {
+			// Parse the file
+			fmt.Printf("  Parsing file: %s\n", filePath)
+			data, err := parser.ParseFile(filePath, contentType)
+			if err != nil {
+				fmt.Printf("Warning: Error parsing %s: %v\n", filePath, err)
 				continue
 			}
 
-			filePath := filepath.Join(dirPath, file.Name())
+			// Files in sections are identified by their path in the content type
+			data.ContentID = parser.ContentIDOf(dirPath, filePath)
 
-			// Parse the file
-			fmt.Printf("  Parsing file: %s\n", filePath)
-			data, err := parser.ParseFile(filePath, contentType)
+			// Drafts stay out of the site until their metadata says otherwise
+			if data.Meta.Draft {
+				fmt.Printf("  Skipping draft: %s\n", filePath)
+				continue
+			}
+
+			// Give each row a stable ID for anchors and saved progress
+			duplicates, err := parser.AssignRowIDs(data, cfg.ContentTypes[contentType].RowKeyFields(data.Headers))
 			if err != nil {
 				fmt.Printf("Warning: Error parsing %s: %v\n", filePath, err)
 				continue
 			}
+			for _, duplicate := range duplicates {
+				fmt.Printf("Warning: %s (row %d) has the same key fields as row %d, given ID %q\n", filePath, duplicate.Row, duplicate.First, duplicate.ID)
+			}
+
+			// Group questions under their reading passages
+			if err := parser.AttachPassages(data, cfg.ContentTypes[contentType].FieldName(config.RolePassage)); err != nil {
+				fmt.Printf("Warning: Error parsing %s: %v\n", filePath, err)
+				continue
+			}
+
+			// Collect the tags of the file and its rows
+			contentTypeConfig := cfg.ContentTypes[contentType]
+			parser.AssignTags(data, contentTypeConfig.FileTags[data.ContentID],
+				contentTypeConfig.FieldName(config.RoleTags), contentTypeConfig.FieldName(config.RoleLevel))
+
+			// Publish the images of the questions
+			problems, err := media.ProcessImages(cfg, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to process images of %s: %w", filePath, err)
+			}
+			for _, problem := range problems {
+				fmt.Printf("Warning: %s (row %d): image %s %s\n", problem.SourcePath, problem.Row, problem.Marker, problem.Message)
+			}
+
+			contents = append(contents, data)
+		}
+	}
+
+	return contents, nil
+}
+
+// generateDerivedContent builds the files of content types derived from
+// other content types, such as quizzes generated from vocabulary decks,
+// returning them for the later build stages
+func generateDerivedContent(cfg *config.Config, contents []*parser.ContentData) ([]*parser.ContentData, error) {
+	var derived []*parser.ContentData
+
+	for contentType, contentTypeConfig := range cfg.ContentTypes {
+		if contentTypeConfig.Derive.From == "" {
+			continue
+		}
+
+		for _, source := range contents {
+			if source.ContentType != contentTypeConfig.Derive.From {
+				continue
+			}
+
+			quizzes, err := quiz.VocabQuizzes(cfg, contentType, source)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, data := range quizzes {
+				// Rows without the fields of a direction, and files too
+				// small to have distractors, leave no questions
+				if len(data.Rows) == 0 {
+					fmt.Printf("Warning: Skipping %s/%s: %s gives it no questions\n", contentType, data.ContentID, source.SourcePath)
+					continue
+				}
+				fmt.Printf("  Generated %s/%s from %s (%d questions)\n", contentType, data.ContentID, source.SourcePath, len(data.Rows))
+				derived = append(derived, data)
+			}
+		}
+	}
+
+	return derived, nil
+}
+
+// generateCollections evaluates the virtual collections of the
+// configuration, returning them for the later build stages
+func generateCollections(cfg *config.Config, contents []*parser.ContentData) ([]*parser.ContentData, error) {
+	collections, err := collection.Build(cfg, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, data := range collections {
+		fmt.Printf("  Generated collection %s/%s (%d rows)\n", data.ContentType, data.ContentID, len(data.Rows))
+	}
+
+	return collections, nil
+}
+
+// renderContentPages renders the page of every data file, derived file and
+// collection
+func renderContentPages(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	for _, data := range contents {
+		outputPath := sitePath(cfg, cfg.ContentPath(data.ContentType, data.ContentID))
+		fmt.Printf("  Rendering template: %s -> %s\n", data.SourcePath, outputPath)
+		if err := template.RenderTemplate(data.ContentType, data, outputPath, cfg, nav); err != nil {
+			return fmt.Errorf("failed to render template %s: %w", outputPath, err)
+		}
+	}
+	return nil
+}
+
+// generateSectionPages writes the index page of every content type and of
+// every section, listing the files and subsections in it
+func generateSectionPages(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	sections := nav.Sections(cfg)
+
+	rowCounts := make(map[string]map[string]int)
+	for _, data := range contents {
+		if rowCounts[data.ContentType] == nil {
+			rowCounts[data.ContentType] = make(map[string]int)
+		}
+		rowCounts[data.ContentType][data.ContentID] = len(data.Rows)
+	}
+
+	for contentType, list := range sections {
+		for _, section := range list {
+			outputPath := sitePath(cfg, cfg.SectionPath(contentType, section.ID))
+			fmt.Printf("  Rendering listing page: %s (%d entries)\n", outputPath, len(section.Children))
+			if err := template.RenderSection(cfg, nav, contentType, section, rowCounts[contentType], outputPath); err != nil {
+				return fmt.Errorf("failed to render section page %s: %w", outputPath, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyMedia copies the media files referenced by the contents into the
+// output and warns about references to files that do not exist
+func copyMedia(cfg *config.Config, contents []*parser.ContentData) error {
+	refs := media.Collect(cfg, contents)
+	copied, problems, err := media.Copy(cfg, refs)
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range problems {
+		if problem.Row > 0 {
+			fmt.Printf("Warning: %s (row %d): media file %q %s\n", problem.SourcePath, problem.Row, problem.Marker, problem.Message)
+		} else {
+			fmt.Printf("Warning: %s (passages): media file %q %s\n", problem.SourcePath, problem.Marker, problem.Message)
+		}
+	}
+	fmt.Printf("  Copied %d media files (%d references)\n", copied, len(refs))
+
+	return nil
+}
+
+// generateExamPages writes a timed exam page for every content file of a
+// content type with exam mode enabled
+func generateExamPages(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	for _, data := range contents {
+		if !cfg.ContentTypes[data.ContentType].Exam.Enabled {
+			continue
+		}
+
+		outputPath := sitePath(cfg, cfg.PagePath(data.ContentType, data.ContentID, "exam"))
+		fmt.Printf("  Rendering exam page: %s\n", outputPath)
+		if err := template.RenderExam(data.ContentType, data, outputPath, cfg, nav); err != nil {
+			return fmt.Errorf("failed to render exam page %s: %w", outputPath, err)
+		}
+	}
+
+	return nil
+}
+
+// checkReferences warns about question references to content files or
+// rows that are not part of the site
+func checkReferences(cfg *config.Config, contents []*parser.ContentData) {
+	targets := make(map[string]bool)
+	for _, data := range contents {
+		target := data.ContentType + "/" + data.ContentID
+		targets[target] = true
+		for _, row := range data.Rows {
+			targets[target+"#"+row[parser.IDField]] = true
+		}
+	}
+
+	for _, data := range contents {
+		// Collections repeat rows already checked in their own files
+		if cfg.IsCollection(data.ContentType, data.ContentID) {
+			continue
+		}
+		field := cfg.ContentTypes[data.ContentType].FieldName(config.RoleReferences)
+		for i, row := range data.Rows {
+			for _, reference := range quiz.ParseReferences(row[field]) {
+				target, anchor, hasAnchor := strings.Cut(reference.Target, "#")
+				if strings.Contains(target, "://") || strings.HasPrefix(target, "/") {
+					continue
+				}
+				target = strings.TrimSuffix(target, ".html")
+				if hasAnchor {
+					target += "#" + anchor
+				}
+				if targets[target] {
+					continue
+				}
+				fmt.Printf("Warning: %s (row %d) refers to missing page %q\n", data.SourcePath, i+1, reference.Target)
+			}
+		}
+	}
+}
+
+// generateVariantPages writes the shuffled test forms and their answer keys
+// for every content file of a content type with variants configured
+func generateVariantPages(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	for _, data := range contents {
+		contentTypeConfig := cfg.ContentTypes[data.ContentType]
+		if contentTypeConfig.Variants <= 0 {
+			continue
+		}
+
+		seed := quiz.BaseSeed(data.ContentType, contentTypeConfig)
+		for _, variant := range quiz.MakeVariants(data, contentTypeConfig, seed) {
+			outputPath := sitePath(cfg, cfg.PagePath(data.ContentType, data.ContentID, variant.Kind()))
+			fmt.Printf("  Rendering variant %d (seed %d): %s\n", variant.Number, variant.Seed, outputPath)
+			if err := template.RenderVariant(data.ContentType, variant, outputPath, cfg, nav); err != nil {
+				return fmt.Errorf("failed to render variant %s: %w", outputPath, err)
+			}
+
+			keyPath := sitePath(cfg, cfg.PagePath(data.ContentType, data.ContentID, variant.KeyKind()))
+			if err := template.RenderAnswerKey(data.ContentType, variant, keyPath, cfg, nav); err != nil {
+				return fmt.Errorf("failed to render answer key %s: %w", keyPath, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// generateStudyPages writes a deck and a study page for every content file
+// of a content type with study mode enabled, plus one covering the whole type
+func generateStudyPages(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	byType := make(map[string][]*parser.ContentData)
+	for _, data := range contents {
+		if cfg.ContentTypes[data.ContentType].Study.Enabled {
+			byType[data.ContentType] = append(byType[data.ContentType], data)
+		}
+	}
 
-			// Generate HTML from template
-			baseName := filepath.Base(file.Name()[:len(file.Name())-len(ext)])
-			outputPath := filepath.Join(
-				cfg.OutputDir,
-				contentType,
-				fmt.Sprintf("%s.html", baseName),
-			)
-
-			// Create output directory if it doesn't exist
-			fmt.Printf("  Creating directory: %s\n", filepath.Dir(outputPath))
-			if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(outputPath), err)
+	for contentType, files := range byType {
+		for _, data := range files {
+			if err := writeStudyPage(cfg, nav, data, data); err != nil {
+				return err
 			}
+		}
 
-			// Render template
-			fmt.Printf("  Rendering template: %s -> %s\n", filePath, outputPath)
-			if err := template.RenderTemplate(contentType, data, outputPath, cfg); err != nil {
-				return fmt.Errorf("failed to render template %s: %w", filePath, err)
+		// The deck covering every file of the content type, leaving out
+		// files with another password than the content type's, such as
+		// quizzes of protected decks, and collections, whose cards are
+		// already in it
+		all := &parser.ContentData{
+			SourcePath:  filepath.Join(cfg.DataDir, contentType),
+			ContentType: contentType,
+			ContentID:   "index",
+		}
+		var shared []*parser.ContentData
+		for _, data := range files {
+			if cfg.PasswordEnv(contentType, data.ContentID) == cfg.ContentTypes[contentType].Password.Env && !cfg.IsCollection(contentType, data.ContentID) {
+				shared = append(shared, data)
 			}
 		}
+		if err := writeStudyPage(cfg, nav, all, shared...); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func generateIndexPage(cfg *config.Config) error {
+// writeStudyPage writes the deck built from files and the study page of page
+func writeStudyPage(cfg *config.Config, nav *template.Nav, page *parser.ContentData, files ...*parser.ContentData) error {
+	deck := study.BuildDeck(cfg, page.ContentType, page.ContentID, files...)
+	deckPath := study.DeckPath(cfg, page.ContentType, page.ContentID)
+	pass, err := cfg.Password(page.ContentType, page.ContentID)
+	if err != nil {
+		return err
+	}
+	if err := study.WriteDeck(sitePath(cfg, deckPath), deck, pass); err != nil {
+		return err
+	}
+
+	outputPath := sitePath(cfg, cfg.PagePath(page.ContentType, page.ContentID, "study"))
+	fmt.Printf("  Rendering study page: %s (%d cards)\n", outputPath, len(deck.Cards))
+	if err := template.RenderStudy(page.ContentType, page, deckPath, outputPath, cfg, nav); err != nil {
+		return fmt.Errorf("failed to render study page %s: %w", outputPath, err)
+	}
+	return nil
+}
+
+// generateKanjiPages writes a page for every kanji of the vocabulary and
+// the page listing them, with KANJIDIC and Hán Việt data when configured
+func generateKanjiPages(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	dicts, err := dictionary.Load(config.DictionaryConfig{
+		KANJIDIC: cfg.Dictionaries.KANJIDIC,
+		HanViet:  cfg.Dictionaries.HanViet,
+	})
+	if err != nil {
+		return err
+	}
+
+	entries := kanji.Build(cfg, contents, dicts)
+	if err := os.MkdirAll(filepath.Join(cfg.OutputDir, config.KanjiDir), 0755); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		outputPath := sitePath(cfg, cfg.KanjiPath(entry.Kanji))
+		if err := template.RenderKanji(cfg, nav, entry, outputPath); err != nil {
+			return fmt.Errorf("failed to render kanji page %s: %w", outputPath, err)
+		}
+	}
+	fmt.Printf("  Rendered %d kanji pages\n", len(entries))
+
+	return template.RenderKanjiIndex(cfg, nav, entries, sitePath(cfg, cfg.KanjiPath("")))
+}
+
+// generateTagPages writes a page for every tag used in the data and the
+// page listing them; nothing is written when no row is tagged
+func generateTagPages(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	tags := taxonomy.Build(cfg, contents)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	for _, tag := range tags {
+		outputPath := sitePath(cfg, cfg.TagPath(tag.Slug))
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return err
+		}
+		if err := template.RenderTag(cfg, nav, tag, outputPath); err != nil {
+			return fmt.Errorf("failed to render tag page %s: %w", outputPath, err)
+		}
+	}
+	fmt.Printf("  Rendered %d tag pages\n", len(tags))
+
+	return template.RenderTagIndex(cfg, nav, tags, sitePath(cfg, cfg.TagPath("")))
+}
+
+// generateSearch writes the sharded search index and the search page
+func generateSearch(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	// Password-protected files stay out of the public index, and so do
+	// derived files and collections, which only repeat other content
+	var public []*parser.ContentData
+	for _, data := range contents {
+		contentTypeConfig := cfg.ContentTypes[data.ContentType]
+		if cfg.PasswordEnv(data.ContentType, data.ContentID) == "" && contentTypeConfig.Derive.From == "" &&
+			!cfg.IsCollection(data.ContentType, data.ContentID) {
+			public = append(public, data)
+		}
+	}
+
+	shards := search.Build(cfg, public)
+	if err := search.Write(filepath.Join(cfg.OutputDir, "search"), shards); err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		fmt.Printf("  Indexed %d rows of %s\n", len(shard.Entries), shard.ContentType)
+	}
+
+	return template.RenderSearch(cfg, nav)
+}
+
+// pagePaths returns the content files and sections by the output file of
+// their page, as /n1/ and /n1/index.html are the same page. Two of them
+// sharing a file, such as n1.csv and the section n1 under a permalink
+// ending in "/", are an error rather than one silently replacing the other.
+func pagePaths(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) (map[string]string, error) {
+	pages := make(map[string]string)
+	addPage := func(contentType, contentID string) error {
+		file := sitePath(cfg, cfg.ContentPath(contentType, contentID))
+		name := contentType + "/" + contentID
+		if other, taken := pages[file]; taken {
+			return fmt.Errorf("%s and %s are both written to %s", other, name, file)
+		}
+		pages[file] = name
+		return nil
+	}
+
+	sections := nav.Sections(cfg)
+	for contentType, items := range sections {
+		for _, section := range items {
+			if err := addPage(contentType, path.Join(section.ID, parser.IndexID)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, data := range contents {
+		if err := addPage(data.ContentType, data.ContentID); err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// generateRedirects writes a page redirecting to the page of a content
+// file from each of its aliases, and to the pages of content types with a
+// permalink pattern from their default paths. An alias at the path of a
+// page, or leading to two pages, is an error.
+func generateRedirects(cfg *config.Config, nav *template.Nav, contents []*parser.ContentData) error {
+	pages, err := pagePaths(cfg, nav, contents)
+	if err != nil {
+		return err
+	}
+	sections := nav.Sections(cfg)
+
+	// Pages old paths lead to, by output file of the old path
+	redirects := make(map[string]string)
+	addRedirect := func(from, contentType, contentID string) error {
+		file := sitePath(cfg, from)
+		page := cfg.ContentPath(contentType, contentID)
+		if pages[file] == contentType+"/"+contentID {
+			return nil
+		}
+		if other, taken := pages[file]; taken {
+			return fmt.Errorf("old path %s of %s/%s is the path of %s", from, contentType, contentID, other)
+		}
+		if other, taken := redirects[file]; taken && other != page {
+			return fmt.Errorf("old path %s leads to both %s and %s", from, other, page)
+		}
+		redirects[file] = page
+		return nil
+	}
+
+	// oldPaths returns the paths a content ID had: under the permalink of
+	// its content type and, with a permalink set, under the default one
+	oldPaths := func(contentType, contentID string) []string {
+		paths := []string{cfg.ContentPath(contentType, contentID)}
+		if cfg.ContentTypes[contentType].Permalink != "" {
+			paths = append(paths, config.ExpandPermalink(config.DefaultPermalink, contentType, contentID))
+		}
+		return paths
+	}
+
+	for contentType, items := range sections {
+		for _, section := range items {
+			id := path.Join(section.ID, parser.IndexID)
+			for _, from := range oldPaths(contentType, id) {
+				if err := addRedirect(from, contentType, id); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, data := range contents {
+		froms := oldPaths(data.ContentType, data.ContentID)
+		for _, alias := range data.Meta.Aliases {
+			alias = strings.TrimSpace(alias)
+			if strings.HasPrefix(alias, "/") {
+				froms = append(froms, alias)
+			} else {
+				froms = append(froms, oldPaths(data.ContentType, strings.TrimSuffix(alias, ".html"))...)
+			}
+		}
+		for _, from := range froms {
+			if err := addRedirect(from, data.ContentType, data.ContentID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for file, page := range redirects {
+		if err := template.RenderRedirect(cfg, cfg.BaseURL+page, file); err != nil {
+			return fmt.Errorf("failed to render redirect %s: %w", file, err)
+		}
+	}
+	if len(redirects) > 0 {
+		fmt.Printf("  Rendered %d redirects\n", len(redirects))
+	}
+	return nil
+}
+
+func generateIndexPage(cfg *config.Config, nav *template.Nav) error {
 	// Generate index page with links to all content
-	return template.RenderIndex(cfg)
+	return template.RenderIndex(cfg, nav)
+}
+
+// sitePath returns the output file for a site-relative URL path, the
+// index.html of the directory for a path ending in "/"
+func sitePath(cfg *config.Config, path string) string {
+	if strings.HasSuffix(path, "/") {
+		path += "index.html"
+	}
+	return filepath.Join(cfg.OutputDir, filepath.FromSlash(path))
 }
 
 // startServer is implemented in server.go 
\ No newline at end of file
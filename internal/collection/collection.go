@@ -0,0 +1,268 @@
+// Package collection evaluates the virtual collections of the configuration:
+// pages gathering the rows of one content type that match a query, across
+// all of its files.
+package collection
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/media"
+	"captoc/internal/natsort"
+	"captoc/internal/parser"
+	"captoc/internal/quiz"
+)
+
+// Build evaluates the configured collections over the parsed contents and
+// returns one content file per collection, ordered by ID. Rows are copied
+// from the files of the collection's content type that share its password,
+// so a public collection never shows protected rows. A row found in several
+// files is kept once; different rows sharing an explicit ID get the ID of
+// their file as a prefix. Passages come along with their questions, and
+// question numbers follow the collection's order.
+func Build(cfg *config.Config, contents []*parser.ContentData) ([]*parser.ContentData, error) {
+	ids := make([]string, 0, len(cfg.Collections))
+	for id := range cfg.Collections {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var collections []*parser.ContentData
+	for _, id := range ids {
+		data, err := build(cfg, id, cfg.Collections[id], contents)
+		if err != nil {
+			return nil, fmt.Errorf("collection %s: %w", id, err)
+		}
+		collections = append(collections, data)
+	}
+	return collections, nil
+}
+
+// build evaluates a single collection
+func build(cfg *config.Config, id string, collection config.CollectionConfig, contents []*parser.ContentData) (*parser.ContentData, error) {
+	contentTypeConfig, ok := cfg.ContentTypes[collection.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown content type %q", collection.Type)
+	}
+
+	data := &parser.ContentData{
+		SourcePath:  filepath.Join(cfg.DataDir, collection.Type),
+		ContentType: collection.Type,
+		ContentID:   id,
+		Meta:        parser.Meta{Title: collection.Title},
+		Images:      make(map[string]*parser.Image),
+		RowTags:     make(map[string][]string),
+	}
+
+	tags := make([]string, 0, len(collection.Tags))
+	for _, tag := range collection.Tags {
+		tags = append(tags, parser.TagSlug(tag))
+	}
+
+	passageField := contentTypeConfig.FieldName(config.RolePassage)
+	questionField := contentTypeConfig.FieldName(config.RoleQuestion)
+	headers := make(map[string]bool)
+	seenRows := make(map[string]map[string]string)
+	seenPassages := make(map[string]bool)
+	for _, source := range contents {
+		if source.ContentType != collection.Type {
+			continue
+		}
+		if source.ContentID == id {
+			return nil, fmt.Errorf("%s has the same ID as the collection", source.SourcePath)
+		}
+		if cfg.PasswordEnv(collection.Type, source.ContentID) != cfg.PasswordEnv(collection.Type, id) {
+			continue
+		}
+
+		passages := make(map[string]*parser.Passage, len(source.Passages))
+		for _, passage := range source.Passages {
+			passages[passage.ID] = passage
+		}
+
+		for _, row := range source.Rows {
+			rowID := row[parser.IDField]
+			if !matchesWhere(row, collection.Where) || !hasTags(source.RowTags[rowID], tags) {
+				continue
+			}
+
+			copied := make(map[string]string, len(row))
+			for key, value := range row {
+				copied[key] = value
+			}
+			// Image paths are relative to the file of the row
+			copied[questionField] = rebaseImages(cfg, data, source, copied[questionField])
+			if seen, ok := seenRows[rowID]; ok {
+				if sameRow(seen, row) {
+					continue
+				}
+				copied[parser.IDField] = source.ContentID + "-" + rowID
+			}
+			seenRows[rowID] = row
+
+			// Passage IDs are only unique within their file
+			if passageID := strings.TrimSpace(row[passageField]); passageID != "" {
+				copied[passageField] = source.ContentID + "/" + passageID
+				if passage, ok := passages[passageID]; ok && !seenPassages[copied[passageField]] {
+					seenPassages[copied[passageField]] = true
+					shared := *passage
+					shared.ID = copied[passageField]
+					data.Passages = append(data.Passages, &shared)
+				}
+			}
+
+			data.Rows = append(data.Rows, copied)
+			if rowTags := source.RowTags[rowID]; len(rowTags) > 0 {
+				data.RowTags[copied[parser.IDField]] = rowTags
+			}
+		}
+
+		for _, header := range source.Headers {
+			if !headers[header] {
+				headers[header] = true
+				data.Headers = append(data.Headers, header)
+			}
+		}
+	}
+
+	sortRows(data.Rows, collection.Sort)
+	if collection.Sample > 0 && collection.Sample < len(data.Rows) {
+		seed := collection.Seed
+		if seed == 0 {
+			seed = quiz.HashSeed(id)
+		}
+		data.Rows = sample(data.Rows, collection.Sample, rand.New(rand.NewSource(seed)))
+	}
+	if collection.Limit > 0 && collection.Limit < len(data.Rows) {
+		data.Rows = data.Rows[:collection.Limit]
+	}
+
+	numberField := contentTypeConfig.FieldName(config.RoleNumber)
+	for i, row := range data.Rows {
+		if _, ok := row[numberField]; ok {
+			row[numberField] = strconv.Itoa(i + 1)
+		}
+	}
+
+	if err := parser.GroupRows(data, passageField); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// sameRow reports whether two rows hold the same values
+func sameRow(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesWhere reports whether a row has the values required by a query
+func matchesWhere(row map[string]string, where map[string]string) bool {
+	for field, condition := range where {
+		if !matchesValue(row[field], condition) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesValue reports whether a value matches one of the "|"-separated
+// alternatives of a condition, each either the whole value or, with a
+// leading "~", part of it
+func matchesValue(value, condition string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, alternative := range strings.Split(condition, "|") {
+		alternative = strings.ToLower(strings.TrimSpace(alternative))
+		if part, ok := strings.CutPrefix(alternative, "~"); ok {
+			if strings.Contains(value, strings.TrimSpace(part)) {
+				return true
+			}
+		} else if value == alternative {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTags reports whether the tags of a row include every wanted slug
+func hasTags(rowTags []string, slugs []string) bool {
+	for _, slug := range slugs {
+		found := false
+		for _, tag := range rowTags {
+			if parser.TagSlug(tag) == slug {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortRows sorts rows by a field in natural order, descending when it
+// starts with "-"
+func sortRows(rows []map[string]string, field string) {
+	if field == "" {
+		return
+	}
+	field, descending := strings.CutPrefix(field, "-")
+	sort.SliceStable(rows, func(i, j int) bool {
+		c := natsort.Compare(strings.TrimSpace(rows[i][field]), strings.TrimSpace(rows[j][field]))
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// rebaseImages rewrites the local image markers of a question copied from
+// source to paths relative to the collection, adding their images to it, so
+// the same path in files of different sections names different images
+func rebaseImages(cfg *config.Config, data, source *parser.ContentData, text string) string {
+	for _, ref := range media.ParseImages(text) {
+		image, ok := source.Images[ref.Marker]
+		if !ok {
+			continue
+		}
+		marker := ref.Marker
+		if config.IsLocalMedia(ref.Path) {
+			rel, err := filepath.Rel(filepath.Dir(data.SourcePath), filepath.Join(filepath.Dir(source.SourcePath), filepath.FromSlash(ref.Path)))
+			if err != nil {
+				continue
+			}
+			marker = "[IMG:" + filepath.ToSlash(rel)
+			if ref.Alt != "" {
+				marker += "|" + ref.Alt
+			}
+			marker += "]"
+			text = strings.ReplaceAll(text, ref.Marker, marker)
+		}
+		data.Images[marker] = image
+	}
+	return text
+}
+
+// sample picks count rows at random, keeping their order
+func sample(rows []map[string]string, count int, rng *rand.Rand) []map[string]string {
+	picked := rng.Perm(len(rows))[:count]
+	sort.Ints(picked)
+	result := make([]map[string]string, 0, count)
+	for _, index := range picked {
+		result = append(result, rows[index])
+	}
+	return result
+}
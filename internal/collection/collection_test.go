@@ -0,0 +1,55 @@
+package collection
+
+import (
+	"path/filepath"
+	"testing"
+
+	"captoc/internal/config"
+	"captoc/internal/parser"
+)
+
+func TestBuildImages(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DataDir = "data"
+	cfg.Collections = map[string]config.CollectionConfig{"tonghop": {Type: "nguphap"}}
+
+	file := func(id, question, url string) *parser.ContentData {
+		return &parser.ContentData{
+			SourcePath:  filepath.Join("data", "nguphap", filepath.FromSlash(id)+".csv"),
+			ContentType: "nguphap",
+			ContentID:   id,
+			Headers:     []string{parser.IDField, "Câu hỏi"},
+			Rows:        []map[string]string{{parser.IDField: id, "Câu hỏi": question}},
+			Images:      map[string]*parser.Image{question: {URL: url}},
+		}
+	}
+	contents := []*parser.ContentData{
+		file("n1/de1", "[IMG:img/a.png]", "/media/nguphap/n1/img/a.png"),
+		file("n2/de1", "[IMG:img/a.png]", "/media/nguphap/n2/img/a.png"),
+		file("n3/de1", "[IMG:https://example.com/a.png|Hình]", "https://example.com/a.png"),
+	}
+
+	collections, err := Build(cfg, contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := collections[0]
+
+	want := []struct{ question, url string }{
+		{"[IMG:nguphap/n1/img/a.png]", "/media/nguphap/n1/img/a.png"},
+		{"[IMG:nguphap/n2/img/a.png]", "/media/nguphap/n2/img/a.png"},
+		{"[IMG:https://example.com/a.png|Hình]", "https://example.com/a.png"},
+	}
+	if len(data.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(data.Rows), len(want))
+	}
+	for i, w := range want {
+		question := data.Rows[i]["Câu hỏi"]
+		if question != w.question {
+			t.Errorf("row %d question = %q, want %q", i+1, question, w.question)
+		}
+		if image := data.Images[question]; image == nil || image.URL != w.url {
+			t.Errorf("row %d image = %+v, want URL %q", i+1, image, w.url)
+		}
+	}
+}
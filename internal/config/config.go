@@ -1,9 +1,17 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
+
+	"captoc/internal/slug"
 )
 
 // Config holds the application configuration
@@ -16,6 +24,9 @@ type Config struct {
 	Theme ThemeConfig `yaml:"theme"`
 	// Navigation menu items
 	Menu []MenuItem `yaml:"menu"`
+	// How the menu is built: manual (the menu items only) or auto (one
+	// item per content type, followed by the menu items)
+	MenuMode string `yaml:"menu_mode,omitempty"`
 	// Content types configuration
 	ContentTypes map[string]ContentTypeConfig `yaml:"content_types"`
 	// Directory where data files are stored
@@ -24,6 +35,91 @@ type Config struct {
 	TemplateDir string `yaml:"template_dir"`
 	// Directory where output files will be written
 	OutputDir string `yaml:"output_dir"`
+	// Processing of images referenced from data files
+	Images ImagesConfig `yaml:"images"`
+	// Local dictionary files used by captoc enrich and kanji pages
+	Dictionaries DictionaryConfig `yaml:"dictionaries"`
+	// Kanji pages generated from vocabulary data
+	Kanji KanjiConfig `yaml:"kanji"`
+	// Pages gathering rows from across the data, by page ID
+	Collections map[string]CollectionConfig `yaml:"collections,omitempty"`
+}
+
+// CollectionConfig describes a virtual collection: a page that is not backed
+// by a data file but gathers the rows of one content type matching a query,
+// such as every N1 verb or every question tagged keigo in any file. It is
+// rendered with the template of its content type, at the path a data file
+// named like the collection would have.
+type CollectionConfig struct {
+	// Page title, defaulting to the collection ID
+	Title string `yaml:"title,omitempty"`
+	// Content type the rows come from
+	Type string `yaml:"type"`
+	// Values rows must have, by field name. Alternatives are separated by
+	// "|" and a leading "~" matches part of the value; case is ignored.
+	Where map[string]string `yaml:"where,omitempty"`
+	// Tags rows must all carry, including levels and file tags
+	Tags []string `yaml:"tags,omitempty"`
+	// Field the rows are sorted by, numbers compared by value, "-field" for
+	// descending; rows keep the data order when empty
+	Sort string `yaml:"sort,omitempty"`
+	// Number of rows picked at random from the matches, keeping their
+	// order; 0 for all
+	Sample int `yaml:"sample,omitempty"`
+	// Seed of the random pick, derived from the collection ID when 0 so the
+	// page stays the same across builds
+	Seed int64 `yaml:"seed,omitempty"`
+	// Maximum number of rows, 0 for no limit
+	Limit int `yaml:"limit,omitempty"`
+}
+
+// KanjiConfig controls the kanji pages, one per kanji used in vocabulary
+// words, listing the words that contain it
+type KanjiConfig struct {
+	// Whether kanji pages are generated
+	Enabled bool `yaml:"enabled"`
+	// Content types whose words are scanned (default tuvung)
+	From []string `yaml:"from,omitempty"`
+	// Title of the kanji index page
+	Title string `yaml:"title,omitempty"`
+}
+
+// TagsDir is the output directory of the tag pages
+const TagsDir = "tags"
+
+// KanjiDir is the output directory of the kanji pages
+const KanjiDir = "kanji"
+
+// Sources returns the content types scanned for kanji
+func (k KanjiConfig) Sources() []string {
+	if len(k.From) == 0 {
+		return []string{"tuvung"}
+	}
+	return k.From
+}
+
+// DictionaryConfig names the local dictionary files
+type DictionaryConfig struct {
+	// JMdict XML file (readings and meanings)
+	JMdict string `yaml:"jmdict,omitempty"`
+	// KANJIDIC2 XML file (kanji readings and stroke counts)
+	KANJIDIC string `yaml:"kanjidic,omitempty"`
+	// CSV table of kanji and their Hán Việt readings
+	HanViet string `yaml:"hanviet,omitempty"`
+	// Language of the JMdict glosses used as meanings (default eng)
+	Language string `yaml:"language,omitempty"`
+}
+
+// ImagesConfig controls how images referenced from data files are copied
+// into the output
+type ImagesConfig struct {
+	// Widths of the resized copies offered in srcset; widths at or above
+	// an image's own width are skipped
+	Widths []int `yaml:"widths,omitempty"`
+	// JPEG quality of the resized copies (1-100)
+	Quality int `yaml:"quality,omitempty"`
+	// sizes attribute of images with resized copies
+	Sizes string `yaml:"sizes,omitempty"`
 }
 
 // ThemeConfig holds the theme configuration
@@ -54,12 +150,23 @@ type ThemeConfig struct {
 	Font string `yaml:"font"`
 }
 
+// Menu modes
+const (
+	MenuManual = "manual"
+	MenuAuto   = "auto"
+)
+
 // MenuItem represents a navigation menu item
 type MenuItem struct {
-	// Label to display in the menu
+	// Label to display in the menu, defaulting to the title of the page
+	// the item refers to
 	Label string `yaml:"label"`
 	// URL to link to
 	URL string `yaml:"url"`
+	// Page to link to instead of a URL: a content type ("tuvung"), or a
+	// file or section of one ("tuvung/n1/bai1") with an optional
+	// "#anchor". References are checked when the site is built.
+	Ref string `yaml:"ref,omitempty"`
 	// Icon name
 	Icon string `yaml:"icon,omitempty"`
 	// Content type this menu item is associated with (optional)
@@ -72,6 +179,8 @@ type MenuItem struct {
 type ContentTypeConfig struct {
 	// Title of the content type
 	Title string `yaml:"title"`
+	// Description shown on the listing page of the content type
+	Description string `yaml:"description,omitempty"`
 	// Template to use for this content type
 	Template string `yaml:"template,omitempty"`
 	// Whether to show search functionality
@@ -82,10 +191,157 @@ type ContentTypeConfig struct {
 	ShowResultImmediately bool `yaml:"show_result_immediately,omitempty"`
 	// Whether to highlight correct answers for quiz-like content
 	HighlightCorrect bool `yaml:"highlight_correct,omitempty"`
+	// When explanations are shown: "check" (after checking an answer, the
+	// default), "reveal" (only when all answers are revealed) or "never"
+	ShowExplanation string `yaml:"show_explanation,omitempty"`
+	// Whether explanations are written in Markdown
+	ExplanationMarkdown bool `yaml:"explanation_markdown,omitempty"`
+	// Which pages keep answers out of the page source: "none" (default),
+	// "exam" (exam pages only) or "all" (practice pages and test forms too)
+	AnswerProtection string `yaml:"answer_protection,omitempty"`
+	// Tags applied to every row of a file, by content ID; rows add their
+	// own in the tags and level columns
+	FileTags map[string][]string `yaml:"file_tags,omitempty"`
+	// How the files are ordered in the navigation: order (metadata order,
+	// then name; the default), name, title or modified (newest first).
+	// Names and titles are compared with numbers by value.
+	Sort string `yaml:"sort,omitempty"`
+	// Content IDs listed first, in this order, ahead of the sorted rest
+	Order []string `yaml:"order,omitempty"`
+	// URL pattern of the pages of the content files, with :type for the
+	// content type, :id for the content ID and :slug for the content ID in
+	// URL form ("n1/Bài_1" → "n1/bai-1"). A pattern ending in "/" writes
+	// each page as the index.html of its own directory. Defaults to
+	// DefaultPermalink; the old default paths then redirect to the pages.
+	Permalink string `yaml:"permalink,omitempty"`
+	// Generates this content type's files from another content type's data
+	// instead of reading a data directory
+	Derive DeriveConfig `yaml:"derive,omitempty"`
+	// Password protection of the pages generated for this content type
+	Password PasswordConfig `yaml:"password,omitempty"`
+	// Question type used for rows without a question type column (single,
+	// multiple, text, ordering or matching; defaults to single)
+	QuestionType string `yaml:"question_type,omitempty"`
+	// Number of shuffled test forms generated per quiz file (0 for none)
+	Variants int `yaml:"variants,omitempty"`
+	// Seed the test forms are shuffled with, so they are reproducible across
+	// builds (derived from the content type name when unset)
+	VariantSeed int64 `yaml:"variant_seed,omitempty"`
+	// Timed exam mode for quiz-like content
+	Exam ExamConfig `yaml:"exam,omitempty"`
+	// Spaced-repetition study mode for card-like content
+	Study StudyConfig `yaml:"study,omitempty"`
+	// Fields hashed into a row's stable ID when the data has no id column
+	// (defaults to the term columns, see RowKeyFields)
+	KeyFields []string `yaml:"key_fields,omitempty"`
 	// Field configurations
 	Fields []FieldConfig `yaml:"fields"`
 }
 
+// StudyConfig holds the spaced-repetition study mode settings
+type StudyConfig struct {
+	// Whether to generate study pages and decks
+	Enabled bool `yaml:"enabled"`
+	// Maximum number of new cards introduced per day
+	NewCardsPerDay int `yaml:"new_cards_per_day,omitempty"`
+	// Fields shown on the front of a card
+	Front []string `yaml:"front,omitempty"`
+	// Fields revealed on the back of a card
+	Back []string `yaml:"back,omitempty"`
+}
+
+// ExamConfig holds the timed exam mode settings
+type ExamConfig struct {
+	// Whether to generate exam pages
+	Enabled bool `yaml:"enabled"`
+	// Time limit in minutes (0 for no limit)
+	TimeLimit int `yaml:"time_limit,omitempty"`
+	// Number of questions drawn for an attempt (0 for all)
+	QuestionCount int `yaml:"question_count,omitempty"`
+	// Whether to shuffle the question order for each attempt
+	Shuffle bool `yaml:"shuffle,omitempty"`
+	// Whether to shuffle the options of each question
+	ShuffleOptions bool `yaml:"shuffle_options,omitempty"`
+}
+
+// DeriveConfig describes a content type generated from another one, such as
+// multiple-choice quizzes built from vocabulary decks
+type DeriveConfig struct {
+	// Content type the data comes from (e.g. tuvung)
+	From string `yaml:"from,omitempty"`
+	// Question directions: meaning (word → meaning), word (meaning → word),
+	// kanji (reading → kanji) and cloze (word blanked out of its example
+	// sentence); defaults to meaning
+	Modes []string `yaml:"modes,omitempty"`
+	// Number of options per question, including the answer (default 4)
+	Options int `yaml:"options,omitempty"`
+}
+
+// Navigation orders of content files
+const (
+	SortOrder    = "order"
+	SortName     = "name"
+	SortTitle    = "title"
+	SortModified = "modified"
+)
+
+// DefaultPermalink is the URL pattern of the pages of content types without
+// one of their own
+const DefaultPermalink = "/:type/:id.html"
+
+// Derive question directions
+const (
+	DeriveMeaning = "meaning"
+	DeriveWord    = "word"
+	DeriveKanji   = "kanji"
+	DeriveCloze   = "cloze"
+)
+
+// ModeList returns the configured question directions, defaulting to
+// word → meaning
+func (d DeriveConfig) ModeList() []string {
+	if len(d.Modes) == 0 {
+		return []string{DeriveMeaning}
+	}
+	return d.Modes
+}
+
+// ContentID returns the ID of the file derived from a source file in one
+// direction. With a single direction it keeps the source ID.
+func (d DeriveConfig) ContentID(sourceID, mode string) string {
+	if len(d.ModeList()) == 1 {
+		return sourceID
+	}
+	return sourceID + "-" + mode
+}
+
+// Title returns the title of the file derived from a source file titled
+// sourceTitle in one direction, empty when the source has no title
+func (d DeriveConfig) Title(sourceTitle, mode string) string {
+	if sourceTitle == "" || len(d.ModeList()) == 1 {
+		return sourceTitle
+	}
+	return sourceTitle + " (" + mode + ")"
+}
+
+// PasswordConfig names the environment variables holding page passwords,
+// so the passwords themselves never end up in the configuration file
+type PasswordConfig struct {
+	// Variable with the password of every file of the content type
+	Env string `yaml:"env,omitempty"`
+	// Variables with the passwords of single files, by content ID
+	Files map[string]string `yaml:"files,omitempty"`
+}
+
+// EnvFor returns the variable holding the password of a content file, or an
+// empty string when the file is public
+func (p PasswordConfig) EnvFor(contentID string) string {
+	if env := p.Files[contentID]; env != "" {
+		return env
+	}
+	return p.Env
+}
+
 // FieldConfig holds configuration for a field
 type FieldConfig struct {
 	// Name of the field
@@ -94,6 +350,126 @@ type FieldConfig struct {
 	Label string `yaml:"label"`
 	// Whether to display the field
 	Display bool `yaml:"display"`
+	// Role of the field for quiz templates and generators (question,
+	// options, answer, number, question_type, passage, explanation,
+	// references) or vocabulary generators and enrich (word, reading,
+	// meaning, word_class, example, sino_vietnamese), or tags and level for
+	// the tag pages; optional when the default column names are used
+	Role string `yaml:"role,omitempty"`
+	// Kind of value: text (default) or audio, a media file path relative to
+	// the data directory (or a URL) rendered as a player
+	Kind string `yaml:"kind,omitempty"`
+}
+
+// Field kinds
+const (
+	KindText  = "text"
+	KindAudio = "audio"
+)
+
+// MediaDir is the output directory media files referenced by data files
+// are copied to
+const MediaDir = "media"
+
+// Field roles understood by templates and generators
+const (
+	RoleNumber         = "number"
+	RoleQuestion       = "question"
+	RoleOptions        = "options"
+	RoleAnswer         = "answer"
+	RoleQuestionType   = "question_type"
+	RolePassage        = "passage"
+	RoleExplanation    = "explanation"
+	RoleReferences     = "references"
+	RoleWord           = "word"
+	RoleReading        = "reading"
+	RoleMeaning        = "meaning"
+	RoleWordClass      = "word_class"
+	RoleExample        = "example"
+	RoleSinoVietnamese = "sino_vietnamese"
+	RoleTags           = "tags"
+	RoleLevel          = "level"
+)
+
+// defaultRoleFields are the column names used for roles no field declares,
+// matching the existing quiz and vocabulary data files
+var defaultRoleFields = map[string]string{
+	RoleNumber:         "Câu số",
+	RoleQuestion:       "Câu hỏi",
+	RoleOptions:        "Lựa chọn",
+	RoleAnswer:         "Đáp án đúng",
+	RoleQuestionType:   "Dạng câu",
+	RolePassage:        "Bài đọc",
+	RoleExplanation:    "Giải thích",
+	RoleReferences:     "Tham khảo",
+	RoleWord:           "japanese",
+	RoleReading:        "reading",
+	RoleMeaning:        "meaning",
+	RoleWordClass:      "wordClass",
+	RoleExample:        "example",
+	RoleSinoVietnamese: "sinoVietnamese",
+	RoleTags:           "tags",
+	RoleLevel:          "level",
+}
+
+// FieldName returns the name of the field playing the given role
+func (c ContentTypeConfig) FieldName(role string) string {
+	for _, field := range c.Fields {
+		if field.Role == role {
+			return field.Name
+		}
+	}
+	return defaultRoleFields[role]
+}
+
+// DisplayFields returns the fields shown for a row, in display order, or
+// all of headers when no field is marked for display
+func (c ContentTypeConfig) DisplayFields(headers []string) []string {
+	var fields []string
+	for _, field := range c.Fields {
+		if field.Display {
+			fields = append(fields, field.Name)
+		}
+	}
+	if len(fields) == 0 {
+		return headers
+	}
+	return fields
+}
+
+// RowKeyFields returns the fields hashed into the IDs of the rows of a file
+// with the given columns: the configured key fields, or else the term
+// columns, the word and its reading or the question and its options, so
+// fixing a meaning or an answer keeps the saved progress of a row. Files
+// with neither use all of their columns.
+func (c ContentTypeConfig) RowKeyFields(headers []string) []string {
+	if len(c.KeyFields) > 0 {
+		return c.KeyFields
+	}
+	for _, term := range [][]string{{RoleWord, RoleReading}, {RoleQuestion, RoleOptions}} {
+		if !slices.Contains(headers, c.FieldName(term[0])) {
+			continue
+		}
+		var fields []string
+		for _, role := range term {
+			if name := c.FieldName(role); slices.Contains(headers, name) {
+				fields = append(fields, name)
+			}
+		}
+		return fields
+	}
+	return nil
+}
+
+// FieldsOfKind returns the names of the fields of a kind, in order
+func (c ContentTypeConfig) FieldsOfKind(kind string) []string {
+	var names []string
+	for _, field := range c.Fields {
+		if field.Kind == kind {
+			names = append(names, field.Name)
+		}
+	}
+	return names
 }
 
 // DefaultConfig returns the default configuration
@@ -121,6 +497,13 @@ func DefaultConfig() *Config {
 	cfg.Theme.InfoColor = "#3b82f6"
 	cfg.Theme.Font = "system-ui, -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Helvetica, Arial, sans-serif"
 
+	// Default kanji pages
+	cfg.Kanji.Title = "Chữ Hán"
+
+	// Default image processing
+	cfg.Images.Quality = 85
+	cfg.Images.Sizes = "(max-width: 768px) 100vw, 768px"
+
 	// Default menu
 	cfg.Menu = []MenuItem{
 		{
@@ -205,6 +588,12 @@ func Load(filename string) (*Config, error) {
 		cfg.OutputDir = "output"
 	}
 
+	for contentType, contentTypeConfig := range cfg.ContentTypes {
+		if err := checkPermalink(contentTypeConfig.Permalink); err != nil {
+			return nil, fmt.Errorf("content type %s: %w", contentType, err)
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -215,4 +604,202 @@ func Save(cfg *Config, filename string) error {
 		return err
 	}
 	return os.WriteFile(filename, data, 0644)
-} 
\ No newline at end of file
+}
+
+// ContentPath returns the site-relative URL of the page generated for a
+// content file, without the base URL
+func (c *Config) ContentPath(contentType, contentID string) string {
+	return c.PagePath(contentType, contentID, "")
+}
+
+// PagePath returns the site-relative URL of an additional page generated
+// for a content file, such as its study page (kind "study"). Pages covering
+// a whole content type use the content ID "index". With a permalink ending
+// in "/" the additional pages are kept in the directory of the page, and
+// the index page of a section is that of the section's directory.
+func (c *Config) PagePath(contentType, contentID, kind string) string {
+	pattern := c.ContentTypes[contentType].Permalink
+	if pattern == "" {
+		pattern = DefaultPermalink
+	}
+
+	if !strings.HasSuffix(pattern, "/") {
+		page := ExpandPermalink(pattern, contentType, contentID)
+		if kind == "" {
+			return page
+		}
+		return strings.TrimSuffix(page, ".html") + "." + kind + ".html"
+	}
+
+	var page string
+	if section, name := path.Split(contentID); name == "index" {
+		page = path.Clean(ExpandPermalink(pattern, contentType, strings.TrimSuffix(section, "/")))
+		page = strings.TrimSuffix(page, "/") + "/"
+	} else {
+		page = ExpandPermalink(pattern, contentType, contentID)
+	}
+	if kind == "" {
+		return page
+	}
+	return page + kind + ".html"
+}
+
+// ExpandPermalink returns the path of the page of a content file under a
+// URL pattern, see ContentTypeConfig.Permalink
+func ExpandPermalink(pattern, contentType, contentID string) string {
+	return strings.NewReplacer(
+		":type", contentType,
+		":id", contentID,
+		":slug", slug.Path(contentID),
+	).Replace(pattern)
+}
+
+// checkPermalink reports whether a URL pattern gives every content file a
+// page of its own
+func checkPermalink(pattern string) error {
+	switch {
+	case pattern == "":
+		return nil
+	case !strings.HasPrefix(pattern, "/"):
+		return fmt.Errorf("permalink %q must start with /", pattern)
+	case !strings.Contains(pattern, ":id") && !strings.Contains(pattern, ":slug"):
+		return fmt.Errorf("permalink %q must contain :id or :slug", pattern)
+	case !strings.HasSuffix(pattern, "/") && !strings.HasSuffix(pattern, ".html"):
+		return fmt.Errorf("permalink %q must end with / or .html", pattern)
+	}
+	return nil
+}
+
+// SectionPath returns the site-relative URL of the index page of a section
+// of a content type, such as n1 for the files in data/tuvung/n1
+func (c *Config) SectionPath(contentType, section string) string {
+	return c.ContentPath(contentType, path.Join(section, "index"))
+}
+
+// IsCollection reports whether a content file is a virtual collection,
+// whose rows repeat those of other files
+func (c *Config) IsCollection(contentType, contentID string) bool {
+	collection, ok := c.Collections[contentID]
+	return ok && collection.Type == contentType
+}
+
+// TagPath returns the site-relative URL of the page of a tag slug. The
+// index page of all tags is TagPath("").
+func (c *Config) TagPath(slug string) string {
+	if slug == "" {
+		return "/" + TagsDir + "/"
+	}
+	return "/" + TagsDir + "/" + slug + "/"
+}
+
+// KanjiPath returns the site-relative URL of the page of a kanji, named by
+// its code point so file names stay ASCII. The index page is KanjiPath("").
+func (c *Config) KanjiPath(kanji string) string {
+	if kanji == "" {
+		return "/" + KanjiDir + "/index.html"
+	}
+	r, _ := utf8.DecodeRuneInString(kanji)
+	return fmt.Sprintf("/%s/%x.html", KanjiDir, r)
+}
+
+// RefURL resolves a reference to another page into a URL with the base URL
+// applied. A reference is a full URL, a site path starting with "/", or a
+// content file as "type/id" with an optional "#anchor" (e.g. a row ID).
+func (c *Config) RefURL(ref string) string {
+	ref = strings.TrimSpace(ref)
+	switch {
+	case strings.HasPrefix(ref, "http://"), strings.HasPrefix(ref, "https://"):
+		return ref
+	case strings.HasPrefix(ref, "/"):
+		return c.BaseURL + ref
+	}
+
+	target, anchor, hasAnchor := strings.Cut(ref, "#")
+	contentType, contentID, ok := strings.Cut(target, "/")
+	if !ok {
+		return ref
+	}
+	url := c.BaseURL + c.ContentPath(contentType, strings.TrimSuffix(contentID, ".html"))
+	if hasAnchor {
+		url += "#" + anchor
+	}
+	return url
+}
+
+// IsLocalMedia reports whether a media reference names a file in the data
+// directory rather than a URL or a path on the site
+func IsLocalMedia(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	return ref != "" && !strings.Contains(ref, "://") && !strings.HasPrefix(ref, "/")
+}
+
+// MediaSource returns the file a local media reference points to. A
+// reference leading out of the data directory, such as ../../etc/passwd,
+// is an error so the file is never published.
+func (c *Config) MediaSource(ref string) (string, error) {
+	clean := filepath.FromSlash(path.Clean(strings.TrimSpace(ref)))
+	if !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("%q is outside the data directory", ref)
+	}
+	return filepath.Join(c.DataDir, clean), nil
+}
+
+// MediaURL resolves a media reference from a data file: local files are
+// served from the media directory, site paths get the base URL and URLs
+// are kept as they are
+func (c *Config) MediaURL(ref string) string {
+	ref = strings.TrimSpace(ref)
+	switch {
+	case ref == "", strings.Contains(ref, "://"):
+		return ref
+	case strings.HasPrefix(ref, "/"):
+		return c.BaseURL + ref
+	}
+	return c.BaseURL + "/" + MediaDir + "/" + path.Clean(ref)
+}
+
+// PasswordEnv returns the variable holding the password of a content file,
+// or an empty string when it is public. A file of a derived content type
+// without a password of its own takes that of its source file, so a quiz
+// never publishes the words of a protected deck in the clear.
+func (c *Config) PasswordEnv(contentType, contentID string) string {
+	contentTypeConfig := c.ContentTypes[contentType]
+	if env := contentTypeConfig.Password.EnvFor(contentID); env != "" {
+		return env
+	}
+
+	derive := contentTypeConfig.Derive
+	if derive.From == "" {
+		return ""
+	}
+	sources := c.ContentTypes[derive.From].Password
+	for _, mode := range derive.ModeList() {
+		sourceID, ok := contentID, true
+		if len(derive.ModeList()) > 1 {
+			sourceID, ok = strings.CutSuffix(contentID, "-"+mode)
+		}
+		if ok {
+			if env := sources.EnvFor(sourceID); env != "" {
+				return env
+			}
+		}
+	}
+	return ""
+}
+
+// Password returns the password protecting the pages of a content file, or
+// an empty string when they are public. A file's own variable wins over the
+// content type's. A configured variable that is not set is an error, so a
+// missing secret never publishes a protected page in the clear.
+func (c *Config) Password(contentType, contentID string) (string, error) {
+	env := c.PasswordEnv(contentType, contentID)
+	if env == "" {
+		return "", nil
+	}
+
+	password := os.Getenv(env)
+	if password == "" {
+		return "", fmt.Errorf("password for %s/%s: environment variable %s is not set", contentType, contentID, env)
+	}
+	return password, nil
+}
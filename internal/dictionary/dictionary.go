@@ -0,0 +1,68 @@
+package dictionary
+
+import (
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/japanese"
+)
+
+// Set is the dictionaries configured for a site; each may be missing
+type Set struct {
+	JMdict   *JMdict
+	KANJIDIC *KANJIDIC
+	HanViet  *HanViet
+}
+
+// Load reads the configured dictionary files
+func Load(cfg config.DictionaryConfig) (*Set, error) {
+	set := &Set{}
+	var err error
+	if cfg.JMdict != "" {
+		if set.JMdict, err = LoadJMdict(cfg.JMdict, cfg.Language); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.KANJIDIC != "" {
+		if set.KANJIDIC, err = LoadKANJIDIC(cfg.KANJIDIC); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.HanViet != "" {
+		if set.HanViet, err = LoadHanViet(cfg.HanViet); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// KanjiHanViet returns the Hán Việt readings of a kanji, from the Hán Việt
+// table when it has the kanji and from KANJIDIC otherwise
+func (s *Set) KanjiHanViet(kanji string) []string {
+	if readings, ok := s.HanViet.Lookup(kanji); ok {
+		return readings
+	}
+	if entry, ok := s.KANJIDIC.Lookup(kanji); ok {
+		return entry.HanViet
+	}
+	return nil
+}
+
+// SinoVietnamese returns the Hán Việt reading of a word, in the upper case
+// used by the vocabulary data (学校 → HỌC HIỆU), from the first reading of
+// each of its kanji. Kana are skipped; a kanji without a reading leaves the
+// word without one.
+func (s *Set) SinoVietnamese(word string) (string, bool) {
+	var parts []string
+	for _, r := range word {
+		if !japanese.IsKanji(r) {
+			continue
+		}
+		readings := s.KanjiHanViet(string(r))
+		if len(readings) == 0 {
+			return "", false
+		}
+		parts = append(parts, strings.ToUpper(readings[0]))
+	}
+	return strings.Join(parts, " "), len(parts) > 0
+}
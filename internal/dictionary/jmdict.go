@@ -0,0 +1,151 @@
+// Package dictionary loads the local dictionary files used to fill in and
+// extend vocabulary data: JMdict for readings and meanings, KANJIDIC2 for
+// kanji readings and stroke counts, and a Hán Việt table.
+package dictionary
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxGlosses is the number of glosses of the first sense kept as a meaning
+const maxGlosses = 3
+
+// Word is a JMdict entry reduced to what vocabulary data needs
+type Word struct {
+	// Reading in kana
+	Reading string
+	// Glosses of the first sense in the configured language
+	Meaning string
+}
+
+// JMdict maps written forms (kanji and kana) to dictionary entries
+type JMdict struct {
+	words map[string]Word
+}
+
+// jmdictEntry is the part of a JMdict <entry> that is read
+type jmdictEntry struct {
+	Kanji    []string `xml:"k_ele>keb"`
+	Readings []struct {
+		Text     string    `xml:"reb"`
+		NoKanji  *struct{} `xml:"re_nokanji"`
+		Restrict []string  `xml:"re_restr"`
+	} `xml:"r_ele"`
+	Senses []struct {
+		Glosses []struct {
+			Lang string `xml:"lang,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"gloss"`
+	} `xml:"sense"`
+}
+
+// entityPattern matches the entity declarations of the JMdict DTD, which
+// the XML decoder does not read by itself
+var entityPattern = regexp.MustCompile(`<!ENTITY\s+(\S+)\s+"([^"]*)">`)
+
+// LoadJMdict reads a JMdict XML file, keeping glosses in language (an ISO
+// 639-2 code such as eng; glosses without a language are English). The
+// first entry of a written form wins, matching the dictionary's ordering.
+func LoadJMdict(path, language string) (*JMdict, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if language == "" {
+		language = "eng"
+	}
+
+	dict := &JMdict{words: make(map[string]Word)}
+	decoder := xml.NewDecoder(file)
+	decoder.Entity = make(map[string]string)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		switch t := token.(type) {
+		case xml.Directive:
+			for _, match := range entityPattern.FindAllStringSubmatch(string(t), -1) {
+				decoder.Entity[match[1]] = match[2]
+			}
+		case xml.StartElement:
+			if t.Name.Local != "entry" {
+				continue
+			}
+			var entry jmdictEntry
+			if err := decoder.DecodeElement(&entry, &t); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			dict.add(entry, language)
+		}
+	}
+
+	return dict, nil
+}
+
+// add indexes an entry under its kanji and kana forms
+func (d *JMdict) add(entry jmdictEntry, language string) {
+	var glosses []string
+	for _, sense := range entry.Senses {
+		for _, gloss := range sense.Glosses {
+			lang := gloss.Lang
+			if lang == "" {
+				lang = "eng"
+			}
+			if lang == language && len(glosses) < maxGlosses {
+				glosses = append(glosses, strings.TrimSpace(gloss.Text))
+			}
+		}
+		if len(glosses) > 0 {
+			break
+		}
+	}
+	meaning := strings.Join(glosses, "; ")
+
+	for _, kanji := range entry.Kanji {
+		if _, ok := d.words[kanji]; ok {
+			continue
+		}
+		for _, reading := range entry.Readings {
+			if reading.NoKanji == nil && (len(reading.Restrict) == 0 || contains(reading.Restrict, kanji)) {
+				d.words[kanji] = Word{Reading: reading.Text, Meaning: meaning}
+				break
+			}
+		}
+	}
+	for _, reading := range entry.Readings {
+		if _, ok := d.words[reading.Text]; !ok {
+			d.words[reading.Text] = Word{Reading: reading.Text, Meaning: meaning}
+		}
+	}
+}
+
+// Lookup returns the entry of a written form
+func (d *JMdict) Lookup(word string) (Word, bool) {
+	if d == nil {
+		return Word{}, false
+	}
+	w, ok := d.words[strings.TrimSpace(word)]
+	return w, ok
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package dictionary
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kanji is a KANJIDIC2 character entry
+type Kanji struct {
+	// The character
+	Literal string
+	// On readings in katakana
+	On []string
+	// Kun readings in hiragana, okurigana after a dot
+	Kun []string
+	// Vietnamese (Hán Việt) readings
+	HanViet []string
+	// English meanings
+	Meanings []string
+	// Stroke count, 0 when unknown
+	Strokes int
+}
+
+// KANJIDIC maps kanji to their KANJIDIC2 entries
+type KANJIDIC struct {
+	kanji map[string]*Kanji
+}
+
+// kanjidicCharacter is the part of a KANJIDIC2 <character> that is read
+type kanjidicCharacter struct {
+	Literal  string `xml:"literal"`
+	Strokes  []int  `xml:"misc>stroke_count"`
+	Readings []struct {
+		Type string `xml:"r_type,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"reading_meaning>rmgroup>reading"`
+	Meanings []struct {
+		Lang string `xml:"m_lang,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"reading_meaning>rmgroup>meaning"`
+}
+
+// LoadKANJIDIC reads a KANJIDIC2 XML file
+func LoadKANJIDIC(path string) (*KANJIDIC, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dict := &KANJIDIC{kanji: make(map[string]*Kanji)}
+	decoder := xml.NewDecoder(file)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "character" {
+			continue
+		}
+		var character kanjidicCharacter
+		if err := decoder.DecodeElement(&character, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		kanji := &Kanji{Literal: character.Literal}
+		// The first stroke count is the accepted one, others are common errors
+		if len(character.Strokes) > 0 {
+			kanji.Strokes = character.Strokes[0]
+		}
+		for _, reading := range character.Readings {
+			switch reading.Type {
+			case "ja_on":
+				kanji.On = append(kanji.On, reading.Text)
+			case "ja_kun":
+				kanji.Kun = append(kanji.Kun, reading.Text)
+			case "vietnam":
+				kanji.HanViet = append(kanji.HanViet, reading.Text)
+			}
+		}
+		for _, meaning := range character.Meanings {
+			if meaning.Lang == "" || meaning.Lang == "en" {
+				kanji.Meanings = append(kanji.Meanings, meaning.Text)
+			}
+		}
+		dict.kanji[kanji.Literal] = kanji
+	}
+
+	return dict, nil
+}
+
+// Lookup returns the entry of a kanji
+func (d *KANJIDIC) Lookup(kanji string) (*Kanji, bool) {
+	if d == nil {
+		return nil, false
+	}
+	k, ok := d.kanji[kanji]
+	return k, ok
+}
+
+// HanViet maps kanji to their Hán Việt readings, most common first
+type HanViet struct {
+	readings map[string][]string
+}
+
+// LoadHanViet reads a Hán Việt table: a CSV file whose first column is a
+// kanji and second its readings, separated by "/" or ";" when there are
+// several. Rows whose first cell is not a single character, such as a
+// header, are skipped.
+func LoadHanViet(path string) (*HanViet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	table := &HanViet{readings: make(map[string][]string)}
+	for _, record := range records {
+		if len(record) < 2 {
+			continue
+		}
+		kanji := strings.TrimSpace(record[0])
+		if utf8.RuneCountInString(kanji) != 1 {
+			continue
+		}
+		for _, reading := range strings.FieldsFunc(record[1], func(r rune) bool { return r == '/' || r == ';' }) {
+			if reading = strings.TrimSpace(reading); reading != "" {
+				table.readings[kanji] = append(table.readings[kanji], reading)
+			}
+		}
+	}
+
+	return table, nil
+}
+
+// Lookup returns the readings of a kanji
+func (t *HanViet) Lookup(kanji string) ([]string, bool) {
+	if t == nil {
+		return nil, false
+	}
+	readings, ok := t.readings[kanji]
+	return readings, ok
+}
@@ -0,0 +1,32 @@
+package enrich
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{
+		{"same", "a\nb\n", "a\nb\n", ""},
+		{
+			name:   "separate hunks",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			after:  "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\nzwölf\n",
+			want:   "--- f\n+++ f\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,4 @@\n 9\n 10\n 11\n-12\n+zwölf\n",
+		},
+		{
+			name:   "close changes in one hunk",
+			before: "a\nb\nc\nd\n",
+			after:  "A\nb\nc\nD\n",
+			want:   "--- f\n+++ f\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n-d\n+D\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Diff("f", []byte(test.before), []byte(test.after)); got != test.want {
+				t.Errorf("Diff = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
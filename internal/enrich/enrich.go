@@ -0,0 +1,119 @@
+// Package enrich fills in the empty reading, meaning and Hán Việt cells of
+// vocabulary data files from local dictionaries and writes the files back
+// in their own format.
+package enrich
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/dictionary"
+)
+
+// Fields are the columns read and filled by enrichment
+type Fields struct {
+	Word           string
+	Reading        string
+	Meaning        string
+	SinoVietnamese string
+}
+
+// FieldsFor returns the enrichment columns of a content type
+func FieldsFor(contentTypeConfig config.ContentTypeConfig) Fields {
+	return Fields{
+		Word:           contentTypeConfig.FieldName(config.RoleWord),
+		Reading:        contentTypeConfig.FieldName(config.RoleReading),
+		Meaning:        contentTypeConfig.FieldName(config.RoleMeaning),
+		SinoVietnamese: contentTypeConfig.FieldName(config.RoleSinoVietnamese),
+	}
+}
+
+// Change is a cell filled in by enrichment
+type Change struct {
+	// Row of the cell (1-based, not counting a CSV header)
+	Row int
+	// Word of the row
+	Word string
+	// Column of the cell
+	Field string
+	// New value
+	Value string
+}
+
+// table is a data file that cells can be read from and written to, kept
+// in its original layout
+type table interface {
+	// Number of rows
+	Len() int
+	// Value of a cell, empty when the column is missing
+	Get(row int, field string) string
+	// Sets a cell, adding the column when it is missing
+	Set(row int, field, value string)
+	// Content of the file with the cells set
+	Bytes() ([]byte, error)
+}
+
+// File fills the empty cells of a data file from the dictionaries and,
+// unless dryRun is set, writes the file back when anything changed. It
+// returns the filled cells and a diff of the file. Words not found in a
+// dictionary leave their cells empty.
+func File(path string, fields Fields, dicts *dictionary.Set, dryRun bool) ([]Change, string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var t table
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		t, err = readCSV(content)
+	case ".json":
+		t, err = readJSON(content)
+	default:
+		return nil, "", fmt.Errorf("unsupported file extension: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var changes []Change
+	for row := 0; row < t.Len(); row++ {
+		word := strings.TrimSpace(t.Get(row, fields.Word))
+		if word == "" {
+			continue
+		}
+
+		fill := func(field, value string) {
+			if field == "" || value == "" || strings.TrimSpace(t.Get(row, field)) != "" {
+				return
+			}
+			t.Set(row, field, value)
+			changes = append(changes, Change{Row: row + 1, Word: word, Field: field, Value: value})
+		}
+
+		if entry, ok := dicts.JMdict.Lookup(word); ok {
+			fill(fields.Reading, entry.Reading)
+			fill(fields.Meaning, entry.Meaning)
+		}
+		if reading, ok := dicts.SinoVietnamese(word); ok {
+			fill(fields.SinoVietnamese, reading)
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, "", nil
+	}
+	enriched, err := t.Bytes()
+	if err != nil {
+		return changes, "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if !dryRun {
+		if err := os.WriteFile(path, enriched, 0644); err != nil {
+			return changes, "", fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return changes, Diff(path, content, enriched), nil
+}
@@ -0,0 +1,141 @@
+package enrich
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"captoc/internal/dictionary"
+)
+
+func TestFileCSV(t *testing.T) {
+	dir := t.TempDir()
+	hanViet := filepath.Join(dir, "hanviet.csv")
+	if err := os.WriteFile(hanViet, []byte("学,học\n校,hiệu\n生,sinh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	table, err := dictionary.LoadHanViet(hanViet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dicts := &dictionary.Set{HanViet: table}
+	fields := Fields{Word: "word", SinoVietnamese: "hanviet"}
+
+	tests := []struct {
+		name    string
+		header  string
+		changes int
+	}{
+		{"without metadata", "", 2},
+		{"with metadata", "# title: Bài 1: Trường học\n# order: 1\n", 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bai1.csv")
+			content := test.header + "word,hanviet\n学校,\n学生,\n"
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			changes, _, err := File(path, fields, dicts, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) != test.changes {
+				t.Errorf("filled %d cells, want %d", len(changes), test.changes)
+			}
+
+			written, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := test.header + "word,hanviet\n学校,HỌC HIỆU\n学生,HỌC SINH\n"
+			if string(written) != want {
+				t.Errorf("wrote %q, want %q", written, want)
+			}
+		})
+	}
+}
+
+func TestFileJSON(t *testing.T) {
+	dir := t.TempDir()
+	hanViet := filepath.Join(dir, "hanviet.csv")
+	if err := os.WriteFile(hanViet, []byte("学,học\n校,hiệu\n生,sinh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	table, err := dictionary.LoadHanViet(hanViet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dicts := &dictionary.Set{HanViet: table}
+	fields := Fields{Word: "word", SinoVietnamese: "hanviet"}
+
+	path := filepath.Join(dir, "bai1.json")
+	content := `{
+    "meta": {"title": "Bài 1"},
+    "items": [
+        {"word": "学校", "hanviet": ""},
+        {"word": "先生", "hanviet": "TIÊN SINH"},
+        {
+            "word": "学生",
+            "meaning": "học sinh"
+        }
+    ]
+}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, diff, err := File(path, fields, dicts, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("filled %d cells, want 2", len(changes))
+	}
+	wantDiff := "--- " + path + "\n+++ " + path + `
+@@ -1,11 +1,12 @@
+ {
+     "meta": {"title": "Bài 1"},
+     "items": [
+-        {"word": "学校", "hanviet": ""},
++        {"word": "学校", "hanviet": "HỌC HIỆU"},
+         {"word": "先生", "hanviet": "TIÊN SINH"},
+         {
+             "word": "学生",
+-            "meaning": "học sinh"
++            "meaning": "học sinh",
++            "hanviet": "HỌC SINH"
+         }
+     ]
+ }
+\ No newline at end of file
+`
+	if diff != wantDiff {
+		t.Errorf("diff = %s, want %s", diff, wantDiff)
+	}
+	if written, _ := os.ReadFile(path); string(written) != content {
+		t.Errorf("dry run wrote %q", written)
+	}
+
+	if _, _, err := File(path, fields, dicts, false); err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(content, `"hanviet": ""`, `"hanviet": "HỌC HIỆU"`, 1)
+	want = strings.Replace(want, `"meaning": "học sinh"`, `"meaning": "học sinh",
+            "hanviet": "HỌC SINH"`, 1)
+	if string(written) != want {
+		t.Errorf("wrote %s, want %s", written, want)
+	}
+
+	// Once filled, nothing changes and the file is left alone
+	changes, diff, err = File(path, fields, dicts, false)
+	if err != nil || len(changes) != 0 || diff != "" {
+		t.Errorf("second run: %d changes, diff %q, error %v", len(changes), diff, err)
+	}
+}
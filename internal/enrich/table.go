@@ -0,0 +1,298 @@
+package enrich
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"captoc/internal/parser"
+)
+
+// csvTable is a CSV file with a header row
+type csvTable struct {
+	// "# key: value" metadata lines above the header, written back as they
+	// were read
+	comments []string
+	header   []string
+	records  [][]string
+}
+
+func readCSV(content []byte) (*csvTable, error) {
+	buffered := bufio.NewReader(bytes.NewReader(content))
+	comments, err := parser.ReadCommentHeader(buffered)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty CSV file")
+	}
+	return &csvTable{comments: comments, header: records[0], records: records[1:]}, nil
+}
+
+func (t *csvTable) Len() int {
+	return len(t.records)
+}
+
+func (t *csvTable) column(field string) int {
+	for i, name := range t.header {
+		if name == field {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *csvTable) Get(row int, field string) string {
+	column := t.column(field)
+	if column < 0 || column >= len(t.records[row]) {
+		return ""
+	}
+	return t.records[row][column]
+}
+
+func (t *csvTable) Set(row int, field, value string) {
+	column := t.column(field)
+	if column < 0 {
+		t.header = append(t.header, field)
+		column = len(t.header) - 1
+	}
+	for len(t.records[row]) <= column {
+		t.records[row] = append(t.records[row], "")
+	}
+	t.records[row][column] = value
+}
+
+func (t *csvTable) Bytes() ([]byte, error) {
+	// Pad short rows so every record has a cell for each column
+	for i := range t.records {
+		for len(t.records[i]) < len(t.header) {
+			t.records[i] = append(t.records[i], "")
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, line := range t.comments {
+		buf.WriteString(line)
+	}
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(t.header); err != nil {
+		return nil, err
+	}
+	if err := writer.WriteAll(t.records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonTable is a JSON file holding an array of row objects, either at the
+// top level or under "items" like the parser accepts. Filled cells are
+// spliced into the file's own text, so its layout and everything else in
+// it are kept.
+type jsonTable struct {
+	content []byte
+	// Items that are not objects are nil and never filled
+	rows []*jsonObject
+}
+
+// jsonObject is a row object and where its members are in the file
+type jsonObject struct {
+	values map[string]json.RawMessage
+	// Offsets of each value in the file
+	spans map[string][2]int
+	// End of the last value, or of the opening brace when there is none
+	end int
+	// Whitespace before a key and between a key and its value, used for
+	// the members added after the last one
+	indent string
+	colon  string
+	// Members set since reading, in order
+	changed []string
+}
+
+func readJSON(content []byte) (*jsonTable, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == json.Delim('{') {
+		// The rows are under "items", other members are skipped
+		token = nil
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			if key == "items" {
+				if token, err = decoder.Token(); err != nil {
+					return nil, err
+				}
+				break
+			}
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if token != json.Delim('[') {
+		return nil, fmt.Errorf("no list of rows")
+	}
+
+	t := &jsonTable{content: content}
+	for decoder.More() {
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return nil, err
+		}
+		var object *jsonObject
+		if item[0] == '{' {
+			if object, err = scanObject(item, int(decoder.InputOffset())-len(item)); err != nil {
+				return nil, err
+			}
+		}
+		t.rows = append(t.rows, object)
+	}
+	return t, nil
+}
+
+// scanObject reads the members of an object found at offset in the file
+func scanObject(raw json.RawMessage, offset int) (*jsonObject, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	object := &jsonObject{
+		values: make(map[string]json.RawMessage),
+		spans:  make(map[string][2]int),
+		end:    offset + 1,
+		colon:  ": ",
+	}
+	for decoder.More() {
+		previous := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+		keyEnd := int(decoder.InputOffset())
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		valueEnd := int(decoder.InputOffset())
+		valueStart := valueEnd - len(value)
+
+		// The key starts after the whitespace and comma that follow the
+		// previous member
+		keyStart := previous
+		for raw[keyStart] != '"' {
+			if raw[keyStart] == ',' {
+				previous = keyStart + 1
+			}
+			keyStart++
+		}
+		object.indent = string(raw[previous:keyStart])
+		object.colon = string(raw[keyEnd:valueStart])
+
+		object.values[key] = value
+		object.spans[key] = [2]int{offset + valueStart, offset + valueEnd}
+		object.end = offset + valueEnd
+	}
+	return object, nil
+}
+
+func (t *jsonTable) Len() int {
+	return len(t.rows)
+}
+
+func (t *jsonTable) Get(row int, field string) string {
+	if t.rows[row] == nil {
+		return ""
+	}
+	raw, ok := t.rows[row].values[field]
+	if !ok {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil || value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (t *jsonTable) Set(row int, field, value string) {
+	object := t.rows[row]
+	if object == nil {
+		return
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return
+	}
+	if !slices.Contains(object.changed, field) {
+		object.changed = append(object.changed, field)
+	}
+	object.values[field] = bytes.TrimSpace(buf.Bytes())
+}
+
+func (t *jsonTable) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	written := 0
+	for _, object := range t.rows {
+		if object == nil || len(object.changed) == 0 {
+			continue
+		}
+
+		// Members are replaced in place, in the order of the file, and
+		// new ones go after the last member
+		var replaced, added []string
+		for _, field := range object.changed {
+			if _, ok := object.spans[field]; ok {
+				replaced = append(replaced, field)
+			} else {
+				added = append(added, field)
+			}
+		}
+		slices.SortFunc(replaced, func(a, b string) int {
+			return object.spans[a][0] - object.spans[b][0]
+		})
+		for _, field := range replaced {
+			span := object.spans[field]
+			buf.Write(t.content[written:span[0]])
+			buf.Write(object.values[field])
+			written = span[1]
+		}
+
+		buf.Write(t.content[written:object.end])
+		written = object.end
+		for i, field := range added {
+			if i > 0 || len(object.spans) > 0 {
+				buf.WriteByte(',')
+			}
+			name, err := json.Marshal(field)
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteString(object.indent)
+			buf.Write(name)
+			buf.WriteString(object.colon)
+			buf.Write(object.values[field])
+		}
+	}
+	buf.Write(t.content[written:])
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,130 @@
+package japanese
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Offset between a hiragana rune and its katakana counterpart
+const kanaOffset = 0x60
+
+// IsHiragana reports whether r is a hiragana character
+func IsHiragana(r rune) bool {
+	return (r >= 0x3041 && r <= 0x3096) || r == 0x309D || r == 0x309E
+}
+
+// IsKatakana reports whether r is a katakana character (full or half width)
+func IsKatakana(r rune) bool {
+	return (r >= 0x30A1 && r <= 0x30FA) || (r >= 0x30FC && r <= 0x30FE) ||
+		(r >= 0xFF66 && r <= 0xFF9F)
+}
+
+// IsKana reports whether r is a hiragana or katakana character
+func IsKana(r rune) bool {
+	return IsHiragana(r) || IsKatakana(r)
+}
+
+// IsKanji reports whether r is a CJK ideograph, including the 々 repeat mark
+func IsKanji(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) || // CJK Unified Ideographs
+		(r >= 0x3400 && r <= 0x4DBF) || // Extension A
+		(r >= 0xF900 && r <= 0xFAFF) || // Compatibility Ideographs
+		(r >= 0x20000 && r <= 0x2FA1F) || // Extensions B and later
+		r == 0x3005
+}
+
+// ContainsKanji reports whether s contains at least one kanji
+func ContainsKanji(s string) bool {
+	return strings.IndexFunc(s, IsKanji) >= 0
+}
+
+// KanjiOf returns the distinct kanji of s in order of appearance, leaving
+// out the 々 repeat mark
+func KanjiOf(s string) []string {
+	var kanji []string
+	seen := make(map[rune]bool)
+	for _, r := range s {
+		if IsKanji(r) && r != 0x3005 && !seen[r] {
+			seen[r] = true
+			kanji = append(kanji, string(r))
+		}
+	}
+	return kanji
+}
+
+// AllKanji reports whether s is non-empty and made of kanji only
+func AllKanji(s string) bool {
+	return all(s, IsKanji)
+}
+
+// AllKana reports whether s is non-empty and made of kana only
+func AllKana(s string) bool {
+	return all(s, IsKana)
+}
+
+// Classify describes the script of s as "kanji", "hiragana", "katakana",
+// "kana" (both syllabaries), "mixed" (kanji and kana) or "other"
+func Classify(s string) string {
+	var kanji, hiragana, katakana, other bool
+	for _, r := range s {
+		switch {
+		case IsKanji(r):
+			kanji = true
+		case IsHiragana(r):
+			hiragana = true
+		case IsKatakana(r):
+			katakana = true
+		default:
+			other = true
+		}
+	}
+
+	switch {
+	case other || s == "":
+		return "other"
+	case kanji && (hiragana || katakana):
+		return "mixed"
+	case kanji:
+		return "kanji"
+	case hiragana && katakana:
+		return "kana"
+	case hiragana:
+		return "hiragana"
+	default:
+		return "katakana"
+	}
+}
+
+// ToKatakana converts every hiragana character in s to katakana
+func ToKatakana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 0x3041 && r <= 0x3096) || r == 0x309D || r == 0x309E {
+			return r + kanaOffset
+		}
+		return r
+	}, s)
+}
+
+// ToHiragana converts every katakana character in s to hiragana.
+// Half-width katakana is widened first.
+func ToHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 0x30A1 && r <= 0x30F6) || r == 0x30FD || r == 0x30FE {
+			return r - kanaOffset
+		}
+		return r
+	}, widenKatakana(s))
+}
+
+// all reports whether s is non-empty and every rune satisfies f
+func all(s string, f func(rune) bool) bool {
+	if s == "" || !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !f(r) {
+			return false
+		}
+	}
+	return true
+}
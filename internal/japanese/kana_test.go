@@ -0,0 +1,66 @@
+package japanese
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"日本", "kanji"},
+		{"人々", "kanji"},
+		{"たべる", "hiragana"},
+		{"カタカナ", "katakana"},
+		{"ｶﾀｶﾅ", "katakana"},
+		{"ひらカナ", "kana"},
+		{"食べる", "mixed"},
+		{"abc", "other"},
+	}
+
+	for _, test := range tests {
+		if got := Classify(test.in); got != test.want {
+			t.Errorf("Classify(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestKanjiOf(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"食べ物", []string{"食", "物"}},
+		// Repeated kanji once, the 々 mark left out
+		{"人々の人生", []string{"人", "生"}},
+		{"たべる", nil},
+	}
+
+	for _, test := range tests {
+		if got := KanjiOf(test.in); !slices.Equal(got, test.want) {
+			t.Errorf("KanjiOf(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestKanaConversion(t *testing.T) {
+	tests := []struct {
+		in, katakana, hiragana string
+	}{
+		{"たべる", "タベル", "たべる"},
+		{"コーヒー", "コーヒー", "こーひー"},
+		{"ｺｰﾋｰ", "ｺｰﾋｰ", "こーひー"},
+		{"ガッコウ", "ガッコウ", "がっこう"},
+		{"日本ご", "日本ゴ", "日本ご"},
+	}
+
+	for _, test := range tests {
+		if got := ToKatakana(test.in); got != test.katakana {
+			t.Errorf("ToKatakana(%q) = %q, want %q", test.in, got, test.katakana)
+		}
+		if got := ToHiragana(test.in); got != test.hiragana {
+			t.Errorf("ToHiragana(%q) = %q, want %q", test.in, got, test.hiragana)
+		}
+	}
+}
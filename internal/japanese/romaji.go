@@ -0,0 +1,234 @@
+package japanese
+
+import (
+	"strings"
+)
+
+// kanaRomaji holds the Hepburn spelling of each hiragana, including the
+// digraphs formed with small kana. Katakana is converted to hiragana first.
+var kanaRomaji = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "ゐ": "i", "ゑ": "e", "を": "o", "ん": "n",
+	"ゔ": "vu",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o",
+	"ゃ": "ya", "ゅ": "yu", "ょ": "yo", "ゎ": "wa",
+
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo",
+	"ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"しゃ": "sha", "しゅ": "shu", "しょ": "sho", "しぇ": "she",
+	"じゃ": "ja", "じゅ": "ju", "じょ": "jo", "じぇ": "je",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "ちぇ": "che",
+	"ぢゃ": "ja", "ぢゅ": "ju", "ぢょ": "jo",
+	"にゃ": "nya", "にゅ": "nyu", "にょ": "nyo",
+	"ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo",
+	"びゃ": "bya", "びゅ": "byu", "びょ": "byo",
+	"ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo",
+	"みゃ": "mya", "みゅ": "myu", "みょ": "myo",
+	"りゃ": "rya", "りゅ": "ryu", "りょ": "ryo",
+
+	// Combinations used for loanwords
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo",
+	"てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du",
+	"うぃ": "wi", "うぇ": "we", "うぉ": "wo", "いぇ": "ye",
+	"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+	"つぁ": "tsa", "つぃ": "tsi", "つぇ": "tse", "つぉ": "tso",
+}
+
+// romajiKana maps romaji syllables to hiragana. It accepts the Hepburn,
+// Kunrei and Nihon-shiki spellings that people commonly type.
+var romajiKana = buildRomajiKana()
+
+func buildRomajiKana() map[string]string {
+	m := make(map[string]string)
+	for kana, romaji := range kanaRomaji {
+		// Prefer the plain kana when several map to the same romaji
+		if existing, ok := m[romaji]; ok && !isPreferredKana(kana, existing) {
+			continue
+		}
+		m[romaji] = kana
+	}
+
+	for romaji, kana := range map[string]string{
+		"si": "し", "ti": "ち", "tu": "つ", "hu": "ふ", "zi": "じ",
+		"di": "ぢ", "du": "づ", "wo": "を", "n'": "ん",
+		"sya": "しゃ", "syu": "しゅ", "syo": "しょ",
+		"tya": "ちゃ", "tyu": "ちゅ", "tyo": "ちょ",
+		"zya": "じゃ", "zyu": "じゅ", "zyo": "じょ",
+		"jya": "じゃ", "jyu": "じゅ", "jyo": "じょ",
+		"cya": "ちゃ", "cyu": "ちゅ", "cyo": "ちょ",
+		"xa": "ぁ", "xi": "ぃ", "xu": "ぅ", "xe": "ぇ", "xo": "ぉ",
+		"la": "ぁ", "li": "ぃ", "lu": "ぅ", "le": "ぇ", "lo": "ぉ",
+		"xya": "ゃ", "xyu": "ゅ", "xyo": "ょ", "xtu": "っ", "xtsu": "っ",
+		"-": "ー",
+	} {
+		m[romaji] = kana
+	}
+	return m
+}
+
+// isPreferredKana decides which of two kana sharing a romaji spelling is
+// produced when converting back from romaji (じ over ぢ, お over を, ...)
+func isPreferredKana(candidate, existing string) bool {
+	rank := func(kana string) int {
+		switch kana {
+		case "ぢ", "づ", "ゐ", "ゑ", "を", "ぢゃ", "ぢゅ", "ぢょ":
+			return 2
+		}
+		if strings.ContainsAny(kana, "ぁぃぅぇぉゃゅょゎ") && len([]rune(kana)) == 1 {
+			return 3
+		}
+		return 1
+	}
+	return rank(candidate) < rank(existing)
+}
+
+// ToRomaji converts kana in s to Hepburn romaji. Long vowels written with
+// ー are spelt out (コーヒー → koohii) so the result can be typed back.
+// Characters that are not kana are kept as they are.
+func ToRomaji(s string) string {
+	runes := []rune(ToHiragana(s))
+
+	var b strings.Builder
+	geminate := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch r {
+		case 'っ':
+			geminate = true
+			continue
+		case 'ー':
+			if prev := b.String(); prev != "" && strings.ContainsRune("aiueo", rune(prev[len(prev)-1])) {
+				b.WriteByte(prev[len(prev)-1])
+			}
+			continue
+		}
+
+		romaji, width := "", 0
+		if i+1 < len(runes) {
+			if digraph, ok := kanaRomaji[string(runes[i:i+2])]; ok {
+				romaji, width = digraph, 2
+			}
+		}
+		if width == 0 {
+			if single, ok := kanaRomaji[string(r)]; ok {
+				romaji, width = single, 1
+			}
+		}
+
+		if width == 0 {
+			geminate = false
+			b.WriteRune(r)
+			continue
+		}
+
+		if geminate {
+			if strings.HasPrefix(romaji, "ch") {
+				b.WriteByte('t')
+			} else if !strings.ContainsRune("aiueon", rune(romaji[0])) {
+				b.WriteByte(romaji[0])
+			}
+			geminate = false
+		}
+
+		// Separate ん from a following vowel or y: きんえん → kin'en
+		if r == 'ん' && i+1 < len(runes) {
+			if next, ok := kanaRomaji[string(runes[i+1])]; ok && strings.ContainsRune("aiueoy", rune(next[0])) {
+				romaji = "n'"
+			}
+		}
+
+		b.WriteString(romaji)
+		i += width - 1
+	}
+	return b.String()
+}
+
+// FromRomaji converts romaji in s to hiragana. Text that cannot be read as
+// romaji is kept as it is.
+func FromRomaji(s string) string {
+	input := strings.ToLower(Normalize(s))
+
+	var b strings.Builder
+	for i := 0; i < len(input); {
+		c := input[i]
+
+		// Double consonants become a small tsu: kk → っk, tch → っch
+		if i+1 < len(input) && isConsonant(c) && c != 'n' &&
+			(input[i+1] == c || (c == 't' && strings.HasPrefix(input[i+1:], "ch"))) {
+			b.WriteString("っ")
+			i++
+			continue
+		}
+
+		// A lone n before a consonant or at the end is ん. "nn" is also ん
+		// unless the second n starts a syllable of its own (onna → おんな).
+		if c == 'n' {
+			next := byte(0)
+			if i+1 < len(input) {
+				next = input[i+1]
+			}
+			switch {
+			case next == 'n':
+				b.WriteString("ん")
+				if i+2 < len(input) && isVowelOrY(input[i+2]) {
+					i++
+				} else {
+					i += 2
+				}
+				continue
+			case next == '\'':
+				b.WriteString("ん")
+				i += 2
+				continue
+			case next == 0 || (!isVowelOrY(next)):
+				b.WriteString("ん")
+				i++
+				continue
+			}
+		}
+
+		matched := false
+		for width := 4; width > 0; width-- {
+			if i+width > len(input) {
+				continue
+			}
+			if kana, ok := romajiKana[input[i:i+width]]; ok {
+				b.WriteString(kana)
+				i += width
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		// Not romaji: copy the whole UTF-8 sequence through
+		r := []rune(input[i:])[0]
+		b.WriteRune(r)
+		i += len(string(r))
+	}
+	return b.String()
+}
+
+func isConsonant(c byte) bool {
+	return c >= 'a' && c <= 'z' && !strings.ContainsRune("aiueo", rune(c))
+}
+
+func isVowelOrY(c byte) bool {
+	return strings.ContainsRune("aiueoy", rune(c))
+}
@@ -0,0 +1,76 @@
+package japanese
+
+import "testing"
+
+func TestToRomaji(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"たべる", "taberu"},
+		{"きょう", "kyou"},
+		{"しんぶん", "shinbun"},
+		// Small tsu doubles the next consonant, ch as tch
+		{"がっこう", "gakkou"},
+		{"きって", "kitte"},
+		{"まっちゃ", "matcha"},
+		// ん before a vowel or y is marked so it reads back the same
+		{"きんえん", "kin'en"},
+		{"こんや", "kon'ya"},
+		{"かんい", "kan'i"},
+		// Long vowels written with ー are spelt out
+		{"コーヒー", "koohii"},
+		{"ラーメン", "raamen"},
+		// Katakana, half width included, and loanword combinations
+		{"ｶﾀｶﾅ", "katakana"},
+		{"パーティー", "paatii"},
+		{"ファイル", "fairu"},
+		// Other characters are kept
+		{"日本ご", "日本go"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := ToRomaji(test.in); got != test.want {
+			t.Errorf("ToRomaji(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestFromRomaji(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"taberu", "たべる"},
+		{"kyou", "きょう"},
+		// Double consonants become a small tsu
+		{"gakkou", "がっこう"},
+		{"matcha", "まっちゃ"},
+		// n is ん before a consonant, at the end, doubled or marked
+		{"shinbun", "しんぶん"},
+		{"hon", "ほん"},
+		{"konnichiha", "こんにちは"},
+		{"onna", "おんな"},
+		{"kin'en", "きんえん"},
+		{"kinen", "きねん"},
+		// Kunrei spellings and full-width or upper-case input
+		{"si", "し"},
+		{"tu", "つ"},
+		{"ＴＡＢＥＲＵ", "たべる"},
+		// Text that is not romaji is kept
+		{"日本go", "日本ご"},
+	}
+
+	for _, test := range tests {
+		if got := FromRomaji(test.in); got != test.want {
+			t.Errorf("FromRomaji(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestRomajiRoundTrip(t *testing.T) {
+	for _, word := range []string{"がっこう", "きんえん", "こんや", "しんぶん", "まっちゃ", "おんな", "じゅぎょう"} {
+		if got := FromRomaji(ToRomaji(word)); got != word {
+			t.Errorf("FromRomaji(ToRomaji(%q)) = %q", word, got)
+		}
+	}
+}
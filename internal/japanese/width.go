@@ -0,0 +1,120 @@
+package japanese
+
+import "strings"
+
+// halfWidthKana maps half-width katakana (U+FF61 to U+FF9F) to full width
+var halfWidthKana = []rune(
+	"。「」、・ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン゛゜",
+)
+
+// fullWidthKana is the reverse of halfWidthKana, including voiced kana
+// which decompose into a base character plus a sound mark
+var fullWidthKana = buildFullWidthKana()
+
+func buildFullWidthKana() map[rune]string {
+	m := make(map[rune]string)
+	for i, r := range halfWidthKana {
+		half := string(rune(0xFF61 + i))
+		m[r] = half
+		if canVoice(r) {
+			m[r+1] = half + "ﾞ"
+		}
+		if canSemiVoice(r) {
+			m[r+2] = half + "ﾟ"
+		}
+	}
+	m['ヴ'] = "ｳﾞ"
+	return m
+}
+
+// canVoice reports whether a katakana takes the dakuten (カ→ガ)
+func canVoice(r rune) bool {
+	return (r >= 'カ' && r <= 'ト' && r != 'ッ') || canSemiVoice(r)
+}
+
+// canSemiVoice reports whether a katakana takes the handakuten (ハ→パ)
+func canSemiVoice(r rune) bool {
+	switch r {
+	case 'ハ', 'ヒ', 'フ', 'ヘ', 'ホ':
+		return true
+	}
+	return false
+}
+
+// widenKatakana converts half-width katakana to full width, folding the
+// separate half-width sound marks into the preceding character
+func widenKatakana(s string) string {
+	if !strings.ContainsFunc(s, isHalfWidthKana) {
+		return s
+	}
+
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if !isHalfWidthKana(r) {
+			b.WriteRune(r)
+			continue
+		}
+
+		full := halfWidthKana[r-0xFF61]
+		if i+1 < len(runes) {
+			switch next := runes[i+1]; {
+			case next == 'ﾞ' && full == 'ウ':
+				full, i = 'ヴ', i+1
+			case next == 'ﾞ' && canVoice(full):
+				full, i = full+1, i+1
+			case next == 'ﾟ' && canSemiVoice(full):
+				full, i = full+2, i+1
+			}
+		}
+		b.WriteRune(full)
+	}
+	return b.String()
+}
+
+func isHalfWidthKana(r rune) bool {
+	return r >= 0xFF61 && r <= 0xFF9F
+}
+
+// Normalize applies the width folding of Unicode NFKC that matters for
+// Japanese text: full-width ASCII letters, digits and punctuation become
+// half width, the ideographic space becomes a regular space and half-width
+// katakana becomes full width.
+func Normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E:
+			return r - 0xFEE0
+		case r == 0x3000:
+			return ' '
+		}
+		return r
+	}, widenKatakana(s))
+}
+
+// ToHalfWidth converts full-width ASCII and katakana to their half-width forms
+func ToHalfWidth(s string) string {
+	var b strings.Builder
+	for _, r := range Normalize(s) {
+		if half, ok := fullWidthKana[r]; ok {
+			b.WriteString(half)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ToFullWidth converts printable ASCII and half-width katakana to full width
+func ToFullWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0x21 && r <= 0x7E:
+			return r + 0xFEE0
+		case r == ' ':
+			return 0x3000
+		}
+		return r
+	}, widenKatakana(s))
+}
@@ -0,0 +1,44 @@
+package japanese
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Ｎ２　ＪＬＰＴ", "N2 JLPT"},
+		{"１２３！", "123!"},
+		// Voiced half-width katakana combine into one character
+		{"ｶﾞｯｺｳ", "ガッコウ"},
+		{"ﾊﾟﾝ", "パン"},
+		{"ｳﾞｧ", "ヴァ"},
+		{"たべる", "たべる"},
+	}
+
+	for _, test := range tests {
+		if got := Normalize(test.in); got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestWidthConversion(t *testing.T) {
+	tests := []struct {
+		in, half, full string
+	}{
+		{"ガッコウ", "ｶﾞｯｺｳ", "ガッコウ"},
+		{"パン", "ﾊﾟﾝ", "パン"},
+		{"N2 abc", "N2 abc", "Ｎ２　ａｂｃ"},
+		{"Ｎ２", "N2", "Ｎ２"},
+		{"ｶﾞｯｺｳ", "ｶﾞｯｺｳ", "ガッコウ"},
+	}
+
+	for _, test := range tests {
+		if got := ToHalfWidth(test.in); got != test.half {
+			t.Errorf("ToHalfWidth(%q) = %q, want %q", test.in, got, test.half)
+		}
+		if got := ToFullWidth(test.in); got != test.full {
+			t.Errorf("ToFullWidth(%q) = %q, want %q", test.in, got, test.full)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package kanji collects the kanji used in vocabulary data for the kanji
+// pages, each listing the words written with it.
+package kanji
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"captoc/internal/config"
+	"captoc/internal/dictionary"
+	"captoc/internal/japanese"
+	"captoc/internal/parser"
+)
+
+// Entry is a kanji and the vocabulary using it
+type Entry struct {
+	// The kanji
+	Kanji string
+	// Hán Việt readings
+	HanViet []string
+	// KANJIDIC entry, nil without a KANJIDIC file or for unlisted kanji
+	Info *dictionary.Kanji
+	// Words containing the kanji, in data order
+	Words []Word
+}
+
+// Word is a vocabulary row listed on a kanji page
+type Word struct {
+	Word    string
+	Reading string
+	Meaning string
+	// URL of the row on its vocabulary page
+	URL string
+}
+
+// Build collects the kanji of the words of the configured content types,
+// most used first. Password-protected files are left out so their words do
+// not show up on public pages, and collections so words are not counted
+// twice. Hán Việt readings come from the dictionaries
+// and, for kanji they lack, from the Hán Việt column of the words when it
+// has one syllable per kanji.
+func Build(cfg *config.Config, contents []*parser.ContentData, dicts *dictionary.Set) []*Entry {
+	sources := make(map[string]bool)
+	for _, contentType := range cfg.Kanji.Sources() {
+		sources[contentType] = true
+	}
+
+	byKanji := make(map[string]*Entry)
+	var entries []*Entry
+	for _, data := range contents {
+		contentTypeConfig := cfg.ContentTypes[data.ContentType]
+		if !sources[data.ContentType] || cfg.IsCollection(data.ContentType, data.ContentID) ||
+			cfg.PasswordEnv(data.ContentType, data.ContentID) != "" {
+			continue
+		}
+
+		wordField := contentTypeConfig.FieldName(config.RoleWord)
+		sinoField := contentTypeConfig.FieldName(config.RoleSinoVietnamese)
+		for _, row := range data.Rows {
+			text := strings.TrimSpace(row[wordField])
+			word := Word{
+				Word:    text,
+				Reading: strings.TrimSpace(row[contentTypeConfig.FieldName(config.RoleReading)]),
+				Meaning: strings.TrimSpace(row[contentTypeConfig.FieldName(config.RoleMeaning)]),
+				URL:     cfg.BaseURL + cfg.ContentPath(data.ContentType, data.ContentID) + "#" + row[parser.IDField],
+			}
+			syllables := alignedSyllables(text, row[sinoField])
+
+			for i, kanji := range japanese.KanjiOf(text) {
+				entry, ok := byKanji[kanji]
+				if !ok {
+					entry = &Entry{Kanji: kanji}
+					entry.Info, _ = dicts.KANJIDIC.Lookup(kanji)
+					entry.HanViet = dicts.KanjiHanViet(kanji)
+					byKanji[kanji] = entry
+					entries = append(entries, entry)
+				}
+				if len(entry.HanViet) == 0 && syllables != nil {
+					entry.HanViet = []string{syllables[i]}
+				}
+				entry.Words = append(entry.Words, word)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if len(entries[i].Words) != len(entries[j].Words) {
+			return len(entries[i].Words) > len(entries[j].Words)
+		}
+		a, _ := utf8.DecodeRuneInString(entries[i].Kanji)
+		b, _ := utf8.DecodeRuneInString(entries[j].Kanji)
+		return a < b
+	})
+	return entries
+}
+
+// alignedSyllables splits the Hán Việt reading of a word into one syllable
+// per distinct kanji, or returns nil when they do not line up (repeated
+// kanji, readings given for kana)
+func alignedSyllables(word, sinoVietnamese string) []string {
+	syllables := strings.Fields(sinoVietnamese)
+	count := 0
+	for _, r := range word {
+		if japanese.IsKanji(r) {
+			count++
+		}
+	}
+	if count == 0 || len(syllables) != count || len(japanese.KanjiOf(word)) != count {
+		return nil
+	}
+	return syllables
+}
@@ -0,0 +1,111 @@
+// Package markdown renders the small Markdown subset used in explanations:
+// paragraphs, line breaks, bullet and numbered lists, bold, italic, inline
+// code and links. The input is HTML-escaped first, so the output is safe to
+// embed in a page.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	codePattern   = regexp.MustCompile("`([^`]+)`")
+	boldPattern   = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicPattern = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+	linkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	bulletPattern = regexp.MustCompile(`^\s*[-*+]\s+`)
+	numberPattern = regexp.MustCompile(`^\s*\d+[.)]\s+`)
+)
+
+// Render converts Markdown text to HTML
+func Render(text string) template.HTML {
+	var out strings.Builder
+	var paragraph []string
+	listTag := ""
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			out.WriteString("</" + listTag + ">")
+			listTag = ""
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+
+		tag, item := "", ""
+		if loc := bulletPattern.FindStringIndex(line); loc != nil {
+			tag, item = "ul", line[loc[1]:]
+		} else if loc := numberPattern.FindStringIndex(line); loc != nil {
+			tag, item = "ol", line[loc[1]:]
+		}
+
+		if tag == "" {
+			closeList()
+			paragraph = append(paragraph, inline(strings.TrimSpace(line)))
+			continue
+		}
+
+		flushParagraph()
+		if listTag != tag {
+			closeList()
+			out.WriteString("<" + tag + ">")
+			listTag = tag
+		}
+		out.WriteString("<li>" + inline(item) + "</li>")
+	}
+	flushParagraph()
+	closeList()
+
+	return template.HTML(out.String())
+}
+
+// inline escapes a line and applies the inline formatting
+func inline(s string) string {
+	s = html.EscapeString(s)
+
+	// Keep code spans out of the other rules
+	var codes []string
+	s = codePattern.ReplaceAllStringFunc(s, func(m string) string {
+		codes = append(codes, "<code>"+codePattern.FindStringSubmatch(m)[1]+"</code>")
+		return "\x00"
+	})
+
+	s = linkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := linkPattern.FindStringSubmatch(m)
+		if !safeURL(html.UnescapeString(parts[2])) {
+			return parts[1]
+		}
+		return `<a href="` + parts[2] + `">` + parts[1] + "</a>"
+	})
+	s = boldPattern.ReplaceAllString(s, "<strong>$1</strong>")
+	s = italicPattern.ReplaceAllString(s, "<em>$1$2</em>")
+
+	for _, code := range codes {
+		s = strings.Replace(s, "\x00", code, 1)
+	}
+	return s
+}
+
+// safeURL reports whether a link target is a web, site-relative or
+// fragment URL, ruling out javascript: and similar schemes
+func safeURL(url string) bool {
+	lower := strings.ToLower(url)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "mailto:") {
+		return true
+	}
+	return !strings.Contains(strings.SplitN(lower, "/", 2)[0], ":")
+}
@@ -0,0 +1,35 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"paragraphs and line breaks", "một\nhai\n\nba", "<p>một<br>hai</p><p>ba</p>"},
+		{"CRLF", "một\r\nhai", "<p>một<br>hai</p>"},
+		{"bold and italic", "**đậm** và *nghiêng* và _nghiêng_", "<p><strong>đậm</strong> và <em>nghiêng</em> và <em>nghiêng</em></p>"},
+		{"underscores inside words", "snake_case_name", "<p>snake_case_name</p>"},
+		{"bullet list", "- một\n* hai\n+ ba", "<ul><li>một</li><li>hai</li><li>ba</li></ul>"},
+		{"numbered list", "1. một\n2) hai", "<ol><li>một</li><li>hai</li></ol>"},
+		{"list after a paragraph", "Ví dụ:\n- một\n\nHết", "<p>Ví dụ:</p><ul><li>một</li></ul><p>Hết</p>"},
+		{"list kinds switching", "- một\n1. hai", "<ul><li>một</li></ul><ol><li>hai</li></ol>"},
+		{"HTML is escaped", "<script>alert(1)</script> & \"x\"", "<p>&lt;script&gt;alert(1)&lt;/script&gt; &amp; &#34;x&#34;</p>"},
+		{"code spans are not formatted", "`**a** <b>`", "<p><code>**a** &lt;b&gt;</code></p>"},
+		{"several code spans", "`a` và `b`", "<p><code>a</code> và <code>b</code></p>"},
+		{"link", "[xem](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2">xem</a></p>`},
+		{"site link", "[bài 1](/tuvung/bai1.html#tu-1)", `<p><a href="/tuvung/bai1.html#tu-1">bài 1</a></p>`},
+		{"javascript link", "[xem](javascript:alert(1))", "<p>xem)</p>"},
+		{"javascript link in upper case", "[xem](JavaScript:alert)", "<p>xem</p>"},
+		{"data link", "[xem](data:text/html,x)", "<p>xem</p>"},
+		{"empty", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(Render(test.in)); got != test.want {
+				t.Errorf("Render(%q) = %q, want %q", test.in, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/parser"
+)
+
+// imagePattern matches the image markers of question text: [IMG:path] or
+// [IMG:path|alt text]
+var imagePattern = regexp.MustCompile(`\[IMG:([^\]|]+)(?:\|([^\]]*))?\]`)
+
+// ImageRef is an image marker found in text
+type ImageRef struct {
+	// Marker as written, the key of the image in ContentData.Images
+	Marker string
+	// Path relative to the data file, site path or URL
+	Path string
+	// Alternative text
+	Alt string
+}
+
+// ParseImages returns the image markers of a text, in order
+func ParseImages(text string) []ImageRef {
+	var refs []ImageRef
+	for _, match := range imagePattern.FindAllStringSubmatch(text, -1) {
+		refs = append(refs, ImageRef{
+			Marker: match[0],
+			Path:   strings.TrimSpace(match[1]),
+			Alt:    strings.TrimSpace(match[2]),
+		})
+	}
+	return refs
+}
+
+// StripImages removes the image markers from a text
+func StripImages(text string) string {
+	return strings.TrimSpace(imagePattern.ReplaceAllString(text, ""))
+}
+
+// Problem is an image or media reference that could not be published as
+// written
+type Problem struct {
+	// Data file of the reference
+	SourcePath string
+	// Row of the reference (1-based), 0 for a passage
+	Row int
+	// Image marker, or media path, as written
+	Marker string
+	// What is wrong with it
+	Message string
+}
+
+// ProcessImages publishes the images referenced from the question text of
+// data's rows and records them in data.Images. Local paths are resolved
+// against the data file's directory and copied into the media directory,
+// with resized copies at the configured widths for JPEG and PNG files;
+// those of files with a password are left to Embed. Missing files, files
+// outside the data directory and images without alternative text are
+// returned as problems rather than errors.
+func ProcessImages(cfg *config.Config, data *parser.ContentData) ([]Problem, error) {
+	field := cfg.ContentTypes[data.ContentType].FieldName(config.RoleQuestion)
+	protected := cfg.PasswordEnv(data.ContentType, data.ContentID) != ""
+
+	var problems []Problem
+	for i, row := range data.Rows {
+		for _, ref := range ParseImages(row[field]) {
+			problem := func(message string) {
+				problems = append(problems, Problem{SourcePath: data.SourcePath, Row: i + 1, Marker: ref.Marker, Message: message})
+			}
+
+			if ref.Alt == "" {
+				problem("has no alt text")
+			}
+			if _, done := data.Images[ref.Marker]; done {
+				continue
+			}
+			if data.Images == nil {
+				data.Images = make(map[string]*parser.Image)
+			}
+
+			if !config.IsLocalMedia(ref.Path) {
+				data.Images[ref.Marker] = &parser.Image{URL: cfg.MediaURL(ref.Path), Alt: ref.Alt}
+				continue
+			}
+
+			// Paths are relative to the data file, resolved like other
+			// media from the data directory
+			rel, err := filepath.Rel(cfg.DataDir, filepath.Join(filepath.Dir(data.SourcePath), filepath.FromSlash(ref.Path)))
+			if err != nil {
+				problem("is outside the data directory")
+				continue
+			}
+			source, err := cfg.MediaSource(filepath.ToSlash(rel))
+			if err != nil {
+				problem("is outside the data directory")
+				continue
+			}
+
+			img := &parser.Image{URL: cfg.MediaURL(filepath.ToSlash(rel)), Alt: ref.Alt}
+			data.Images[ref.Marker] = img
+
+			info, err := os.Stat(source)
+			if err != nil || info.IsDir() {
+				problem("not found")
+				continue
+			}
+			// Images of files with a password are embedded in their
+			// encrypted pages, see Embed
+			if protected {
+				continue
+			}
+			if err := publishImage(cfg, source, rel, info, img); err != nil {
+				return problems, err
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// publishImage copies an image into the media directory, writes its
+// resized copies and fills in its size and srcset
+func publishImage(cfg *config.Config, source, rel string, info os.FileInfo, img *parser.Image) error {
+	target := filepath.Join(cfg.OutputDir, config.MediaDir, rel)
+	if err := publish(source, target, info); err != nil {
+		return err
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", source, err)
+	}
+	defer file.Close()
+
+	// Formats the standard library cannot read (SVG, WebP) are only copied
+	header, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil
+	}
+	img.Width, img.Height = header.Width, header.Height
+	if format != "jpeg" && format != "png" {
+		return nil
+	}
+
+	var decoded image.Image
+	var srcset []string
+	for _, width := range cfg.Images.Widths {
+		if width <= 0 || width >= img.Width {
+			continue
+		}
+
+		ext := filepath.Ext(rel)
+		resizedRel := fmt.Sprintf("%s-%dw%s", strings.TrimSuffix(rel, ext), width, ext)
+		resizedTarget := filepath.Join(cfg.OutputDir, config.MediaDir, resizedRel)
+		srcset = append(srcset, fmt.Sprintf("%s %dw", cfg.MediaURL(filepath.ToSlash(resizedRel)), width))
+
+		if unchanged(resizedTarget, info, false) {
+			continue
+		}
+		if decoded == nil {
+			if _, err := file.Seek(0, 0); err != nil {
+				return fmt.Errorf("error reading file %s: %w", source, err)
+			}
+			if decoded, _, err = image.Decode(file); err != nil {
+				return fmt.Errorf("error decoding image %s: %w", source, err)
+			}
+		}
+		if err := writeResized(resizedTarget, resize(decoded, width), format, cfg.Images.Quality, info); err != nil {
+			return err
+		}
+	}
+
+	if len(srcset) > 0 {
+		srcset = append(srcset, fmt.Sprintf("%s %dw", img.URL, img.Width))
+		img.SrcSet = strings.Join(srcset, ", ")
+	}
+	return nil
+}
+
+// writeResized encodes a resized image in the format of its source and
+// gives it the source's modification time, so later builds can skip it
+func writeResized(target string, img image.Image, format string, quality int, info os.FileInfo) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", filepath.Dir(target), err)
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("error writing file %s: %w", target, err)
+	}
+	if format == "png" {
+		err = png.Encode(out, img)
+	} else {
+		err = jpeg.Encode(out, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("error writing file %s: %w", target, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("error writing file %s: %w", target, err)
+	}
+
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
+
+// resize scales an image down to width pixels, keeping its aspect ratio,
+// by averaging the source pixels covered by each target pixel
+func resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
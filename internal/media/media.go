@@ -0,0 +1,208 @@
+// Package media copies the audio and image files referenced by data files
+// into the output, so media can live next to the data it belongs to.
+package media
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/parser"
+)
+
+// Ref is a media file referenced from a data file
+type Ref struct {
+	// Reference as written in the data file
+	Path string
+	// Data file the reference is in
+	SourcePath string
+	// Row of the reference (1-based), 0 for a passage
+	Row int
+}
+
+// Collect returns the local media references of the contents: the values
+// of their audio fields and the recordings of their passages. Collections
+// are skipped, their rows being those of other files, and so are files
+// with a password, whose media are embedded in their encrypted pages by
+// Embed instead of being published.
+func Collect(cfg *config.Config, contents []*parser.ContentData) []Ref {
+	var refs []Ref
+	for _, data := range contents {
+		if cfg.IsCollection(data.ContentType, data.ContentID) || cfg.PasswordEnv(data.ContentType, data.ContentID) != "" {
+			continue
+		}
+		fields := cfg.ContentTypes[data.ContentType].FieldsOfKind(config.KindAudio)
+		for i, row := range data.Rows {
+			for _, field := range fields {
+				if config.IsLocalMedia(row[field]) {
+					refs = append(refs, Ref{Path: row[field], SourcePath: data.SourcePath, Row: i + 1})
+				}
+			}
+		}
+		for _, passage := range data.Passages {
+			if config.IsLocalMedia(passage.Audio) {
+				refs = append(refs, Ref{Path: passage.Audio, SourcePath: data.SourcePath})
+			}
+		}
+	}
+	return refs
+}
+
+// Copy copies the referenced files into the media directory of the output.
+// Files already there with the same size and modification time are left
+// alone, so rebuilding does not rewrite large recordings. It returns the
+// number of files copied and the problems of the references to files that
+// do not exist or lie outside the data directory, which are not copied.
+func Copy(cfg *config.Config, refs []Ref) (int, []Problem, error) {
+	copied := 0
+	var problems []Problem
+	done := make(map[string]bool)
+
+	for _, ref := range refs {
+		problem := func(message string) {
+			problems = append(problems, Problem{SourcePath: ref.SourcePath, Row: ref.Row, Marker: ref.Path, Message: message})
+		}
+
+		clean := path.Clean(strings.TrimSpace(ref.Path))
+		source, err := cfg.MediaSource(clean)
+		if err != nil {
+			problem("is outside the data directory")
+			continue
+		}
+		info, err := os.Stat(source)
+		if err != nil || info.IsDir() {
+			problem("not found")
+			continue
+		}
+		if done[clean] {
+			continue
+		}
+		done[clean] = true
+
+		target := filepath.Join(cfg.OutputDir, config.MediaDir, filepath.FromSlash(clean))
+		if unchanged(target, info, true) {
+			continue
+		}
+		if err := copyFile(source, target, info); err != nil {
+			return copied, problems, err
+		}
+		copied++
+	}
+
+	return copied, problems, nil
+}
+
+// Embed replaces the links of a page to files of the media directory with
+// the files themselves as data URLs, so a password-protected page carries
+// its media inside its encryption rather than pointing to public copies.
+// Links to files that do not exist are left as they are.
+func Embed(cfg *config.Config, page []byte) ([]byte, error) {
+	prefix := regexp.QuoteMeta(cfg.BaseURL + "/" + config.MediaDir + "/")
+	links := regexp.MustCompile(`(src|href)="` + prefix + `([^"#?]+)"`)
+
+	var err error
+	embedded := make(map[string]string)
+	page = links.ReplaceAllFunc(page, func(link []byte) []byte {
+		match := links.FindSubmatch(link)
+		ref, unescapeErr := url.PathUnescape(html.UnescapeString(string(match[2])))
+		if unescapeErr != nil {
+			return link
+		}
+		data, ok := embedded[ref]
+		if !ok {
+			source, sourceErr := cfg.MediaSource(ref)
+			if sourceErr != nil {
+				return link
+			}
+			content, readErr := os.ReadFile(source)
+			if os.IsNotExist(readErr) {
+				return link
+			}
+			if readErr != nil {
+				err = readErr
+				return link
+			}
+			kind := audioTypes[strings.ToLower(path.Ext(ref))]
+			if kind == "" {
+				kind = mime.TypeByExtension(path.Ext(ref))
+			}
+			if kind == "" {
+				kind = "application/octet-stream"
+			}
+			data = "data:" + kind + ";base64," + base64.StdEncoding.EncodeToString(content)
+			embedded[ref] = data
+		}
+		return []byte(string(match[1]) + `="` + data + `"`)
+	})
+	return page, err
+}
+
+// audioTypes are the types of recordings, which the standard library only
+// knows from the system's tables
+var audioTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".webm": "audio/webm",
+}
+
+// publish copies a file into the output unless an unchanged copy is
+// already there
+func publish(source, target string, info os.FileInfo) error {
+	if unchanged(target, info, true) {
+		return nil
+	}
+	return copyFile(source, target, info)
+}
+
+// unchanged reports whether target was written from the current version of
+// a source file: copies and resized files carry the source's modification
+// time, and copies its size too
+func unchanged(target string, info os.FileInfo, sameSize bool) bool {
+	existing, err := os.Stat(target)
+	if err != nil {
+		return false
+	}
+	return existing.ModTime().Equal(info.ModTime()) && (!sameSize || existing.Size() == info.Size())
+}
+
+// copyFile copies a file and gives the copy the source's modification time
+func copyFile(source, target string, info os.FileInfo) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", filepath.Dir(target), err)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", source, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("error writing file %s: %w", target, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("error writing file %s: %w", target, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("error writing file %s: %w", target, err)
+	}
+
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
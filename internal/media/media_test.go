@@ -0,0 +1,75 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"captoc/internal/config"
+	"captoc/internal/parser"
+)
+
+func TestProtectedMedia(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.DataDir = filepath.Join(dir, "data")
+	cfg.OutputDir = filepath.Join(dir, "docs")
+	cfg.BaseURL = "/site"
+	cfg.ContentTypes["nguphap"] = config.ContentTypeConfig{
+		Password: config.PasswordConfig{Files: map[string]string{"khoa": "CAPTOC_TEST_PASSWORD"}},
+		Fields:   []config.FieldConfig{{Name: "audio", Kind: config.KindAudio}},
+	}
+
+	for name, content := range map[string]string{"nguphap/a.mp3": "audio", "nguphap/b.png": "image"} {
+		file := filepath.Join(cfg.DataDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file := func(id string) *parser.ContentData {
+		return &parser.ContentData{
+			SourcePath:  filepath.Join(cfg.DataDir, "nguphap", id+".csv"),
+			ContentType: "nguphap",
+			ContentID:   id,
+			Rows:        []map[string]string{{"audio": "nguphap/a.mp3", "Câu hỏi": "[IMG:b.png|Hình]"}},
+		}
+	}
+	public, protected := file("mo"), file("khoa")
+
+	refs := Collect(cfg, []*parser.ContentData{public, protected})
+	if len(refs) != 1 || refs[0].SourcePath != public.SourcePath {
+		t.Errorf("Collect = %+v, want the reference of the public file only", refs)
+	}
+
+	// The images of the protected file are not published
+	if _, err := ProcessImages(cfg, protected); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, config.MediaDir, "nguphap", "b.png")); !os.IsNotExist(err) {
+		t.Errorf("image of a protected file published: %v", err)
+	}
+	if got := protected.Images["[IMG:b.png|Hình]"].URL; got != "/site/media/nguphap/b.png" {
+		t.Errorf("image URL = %q", got)
+	}
+
+	page := []byte(`<audio src="/site/media/nguphap/a.mp3"><a href="/site/media/nguphap/a.mp3">x</a></audio>` +
+		`<img src="/site/media/nguphap/b.png"><img src="/site/media/nguphap/missing.png">`)
+	embedded, err := Embed(cfg, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<audio src="data:audio/mpeg;base64,YXVkaW8="><a href="data:audio/mpeg;base64,YXVkaW8=">`,
+		`<img src="data:image/png;base64,aW1hZ2U=">`,
+		`<img src="/site/media/nguphap/missing.png">`,
+	} {
+		if !strings.Contains(string(embedded), want) {
+			t.Errorf("Embed = %s, want it to contain %s", embedded, want)
+		}
+	}
+}
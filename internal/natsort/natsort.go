@@ -0,0 +1,78 @@
+// Package natsort compares strings in natural order, with runs of digits
+// compared by their numeric value so n1_2 sorts before n1_10.
+package natsort
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Compare returns -1, 0 or 1 as a sorts before, equal to or after b in
+// natural order. Letters are compared without case; strings differing only
+// in case or leading zeros are then ordered by plain comparison so the
+// order is total.
+func Compare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		ra, sa := utf8.DecodeRuneInString(a[i:])
+		rb, sb := utf8.DecodeRuneInString(b[j:])
+
+		if isDigit(ra) && isDigit(rb) {
+			endA, endB := digitsEnd(a, i), digitsEnd(b, j)
+			if c := compareNumbers(a[i:endA], b[j:endB]); c != 0 {
+				return c
+			}
+			i, j = endA, endB
+			continue
+		}
+
+		la, lb := unicode.ToLower(ra), unicode.ToLower(rb)
+		if la != lb {
+			if la < lb {
+				return -1
+			}
+			return 1
+		}
+		i += sa
+		j += sb
+	}
+
+	switch {
+	case len(a)-i < len(b)-j:
+		return -1
+	case len(a)-i > len(b)-j:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Less reports whether a sorts before b in natural order
+func Less(a, b string) bool {
+	return Compare(a, b) < 0
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// digitsEnd returns the end of the run of digits starting at i
+func digitsEnd(s string, i int) int {
+	for i < len(s) && isDigit(rune(s[i])) {
+		i++
+	}
+	return i
+}
+
+// compareNumbers compares two runs of digits by value
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
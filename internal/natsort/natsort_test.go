@@ -0,0 +1,49 @@
+package natsort
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"n1_2", "n1_10", -1},
+		{"n1_10", "n1_2", 1},
+		{"bai9", "bai10", -1},
+		{"bai10", "bai10", 0},
+		// Letters without case, then plain order to keep the order total
+		{"Bai1", "bai2", -1},
+		{"Bai1", "bai1", -1},
+		{"bai1", "Bai1", 1},
+		// Leading zeros compare by value, then plainly
+		{"bai010", "bai9", 1},
+		{"bai01", "bai1", -1},
+		// A prefix sorts first
+		{"bai", "bai1", -1},
+		{"", "a", -1},
+		// Numbers longer than an int
+		{"x12345678901234567890", "x99999999999999999999", -1},
+		// Non-ASCII letters
+		{"đề 2", "đề 10", -1},
+	}
+
+	for _, test := range tests {
+		if got := Compare(test.a, test.b); got != test.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	names := []string{"n1_10", "n1_2", "N1_1", "n1", "n1_2a", "n2_1"}
+	want := []string{"n1", "N1_1", "n1_2", "n1_2a", "n1_10", "n2_1"}
+
+	sort.Slice(names, func(i, j int) bool { return Less(names[i], names[j]) })
+	if !slices.Equal(names, want) {
+		t.Errorf("sorted = %q, want %q", names, want)
+	}
+}
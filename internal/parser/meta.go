@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetaSuffix marks the sidecar file holding the metadata of the content file
+// with the same base name, e.g. so1.meta.yaml for so1.csv, or of the section
+// directory next to it, e.g. n1.meta.yaml for n1/
+const MetaSuffix = ".meta.yaml"
+
+// MetaKey is the key of the metadata in a JSON file whose rows are under
+// "items"
+const MetaKey = "meta"
+
+// Meta is the metadata of a content file. It is written at the top of CSV
+// files as "# key: value" comment lines, under MetaKey in JSON files, or in
+// a sidecar file; values in the sidecar win.
+type Meta struct {
+	// Title shown instead of the file name
+	Title string `yaml:"title,omitempty"`
+	// Short description shown under the title
+	Description string `yaml:"description,omitempty"`
+	// Position in the navigation: files with an order come first, lowest
+	// first, followed by the others by name
+	Order int `yaml:"order,omitempty"`
+	// Draft files and sections are left out of the site
+	Draft bool `yaml:"draft,omitempty"`
+	// Page template used instead of the content type's
+	Template string `yaml:"template,omitempty"`
+	// Old paths of the page, redirecting to it so shared links keep
+	// working after a rename: site paths such as /tuvung/bai1.html, or old
+	// content IDs of the same content type
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+// ReadMeta reads the metadata of a content file without parsing its rows,
+// or that of a section directory
+func ReadMeta(filePath string) (Meta, error) {
+	var meta Meta
+	if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+		return meta, readMetaSidecar(filePath+MetaSuffix, &meta)
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".csv":
+		file, err := os.Open(filePath)
+		if err != nil {
+			return meta, err
+		}
+		defer file.Close()
+
+		header, err := ReadCommentHeader(bufio.NewReader(file))
+		if err != nil {
+			return meta, err
+		}
+		if err := headerNode(header).Decode(&meta); err != nil {
+			return meta, fmt.Errorf("invalid metadata in %s: %w", filePath, err)
+		}
+	case ".json":
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return meta, err
+		}
+		var document struct {
+			Meta json.RawMessage `json:"meta"`
+		}
+		// Files holding a bare array have no metadata
+		if json.Unmarshal(content, &document) == nil && len(document.Meta) > 0 {
+			if err := yaml.Unmarshal(document.Meta, &meta); err != nil {
+				return meta, fmt.Errorf("invalid metadata in %s: %w", filePath, err)
+			}
+		}
+	}
+
+	return meta, readMetaSidecar(strings.TrimSuffix(filePath, filepath.Ext(filePath))+MetaSuffix, &meta)
+}
+
+// readMetaSidecar overlays a metadata sidecar, if there is one
+func readMetaSidecar(path string, meta *Meta) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(content, meta); err != nil {
+		return fmt.Errorf("invalid metadata in %s: %w", path, err)
+	}
+	return nil
+}
+
+// metaLine matches a "# key: value" metadata line at the top of a CSV file
+var metaLine = regexp.MustCompile(`^# [A-Za-z_][A-Za-z0-9_-]*:(\s|$)`)
+
+// ReadCommentHeader consumes the "# key: value" lines at the top of a CSV
+// file and returns them as written, line endings included, leaving the
+// reader at the CSV header. Other lines starting with "#", such as a header
+// whose first column is "#" or "#id", are left to the CSV parser.
+func ReadCommentHeader(reader *bufio.Reader) ([]string, error) {
+	var header []string
+	for {
+		// A read error shows up again when the line is read
+		next, _ := reader.Peek(reader.Size())
+		if i := bytes.IndexByte(next, '\n'); i >= 0 {
+			next = next[:i]
+		}
+		if !metaLine.Match(next) {
+			return header, nil
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return nil, err
+		}
+		header = append(header, line)
+	}
+}
+
+// headerNode turns "# key: value" lines into a mapping to decode into Meta.
+// The value is everything after the first colon, taken as written, so a
+// title such as "Bài 2: Động từ" needs no quotes; a list field also takes
+// a bracketed, comma-separated list ("aliases: [bai2, /cu/bai2.html]").
+func headerNode(lines []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, line := range lines {
+		line = strings.TrimPrefix(strings.TrimRight(line, "\r\n"), "# ")
+		key, value, _ := strings.Cut(line, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		var valueNode *yaml.Node
+		if metaLists[key] {
+			valueNode = &yaml.Node{Kind: yaml.SequenceNode}
+			if inner, ok := strings.CutPrefix(value, "["); ok {
+				value = strings.TrimSuffix(inner, "]")
+			}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.Trim(strings.TrimSpace(item), `"'`); item != "" {
+					valueNode.Content = append(valueNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+				}
+			}
+		} else {
+			// Quotes around a value, needed when the header was YAML
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+			valueNode = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			if metaStrings[key] {
+				valueNode.Tag = "!!str"
+			}
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, valueNode)
+	}
+	return node
+}
+
+// metaStrings and metaLists are the keys of the text and list fields of
+// Meta, whose values in a CSV header are never read as numbers or booleans
+var metaStrings, metaLists = metaKinds()
+
+func metaKinds() (strs, lists map[string]bool) {
+	strs, lists = make(map[string]bool), make(map[string]bool)
+	t := reflect.TypeFor[Meta]()
+	for i := range t.NumField() {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		switch t.Field(i).Type.Kind() {
+		case reflect.String:
+			strs[key] = true
+		case reflect.Slice:
+			lists[key] = true
+		}
+	}
+	return strs, lists
+}
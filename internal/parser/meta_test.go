@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCSVCommentHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		meta    Meta
+		headers []string
+		rows    int
+	}{
+		{
+			name:    "metadata",
+			content: "# title: Bài 1\n# order: 2\n# aliases: [bai_cu]\nword,meaning\n食べる,ăn\n",
+			meta:    Meta{Title: "Bài 1", Order: 2, Aliases: []string{"bai_cu"}},
+			headers: []string{"word", "meaning"},
+			rows:    1,
+		},
+		{
+			name:    "values with colons and quotes",
+			content: "# title: Bài 2: Động từ\n# description: \"Ôn tập\"\n# draft: false\n# aliases: bai2, /cu/bai2.html\nword\n食べる\n",
+			meta:    Meta{Title: "Bài 2: Động từ", Description: "Ôn tập", Aliases: []string{"bai2", "/cu/bai2.html"}},
+			headers: []string{"word"},
+			rows:    1,
+		},
+		{
+			name:    "title that looks like a number",
+			content: "# title: 2024\nword\n食べる\n",
+			meta:    Meta{Title: "2024"},
+			headers: []string{"word"},
+			rows:    1,
+		},
+		{
+			name:    "metadata with CRLF",
+			content: "# title: Bài 1\r\nword,meaning\r\n食べる,ăn\r\n",
+			meta:    Meta{Title: "Bài 1"},
+			headers: []string{"word", "meaning"},
+			rows:    1,
+		},
+		{
+			name:    "header starting with #",
+			content: "#,word,meaning\n1,食べる,ăn\n2,飲む,uống\n",
+			headers: []string{"#", "word", "meaning"},
+			rows:    2,
+		},
+		{
+			name:    "header starting with #id",
+			content: "#id,word\n1,食べる\n",
+			headers: []string{"#id", "word"},
+			rows:    1,
+		},
+		{
+			name:    "comment that is not metadata",
+			content: "# word,meaning\n食べる,ăn\n",
+			headers: []string{"# word", "meaning"},
+			rows:    1,
+		},
+		{
+			name:    "metadata before a header starting with #",
+			content: "# title: Bài 1\n#,word\n1,食べる\n",
+			meta:    Meta{Title: "Bài 1"},
+			headers: []string{"#", "word"},
+			rows:    1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "bai1.csv")
+			if err := os.WriteFile(filePath, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			meta, err := ReadMeta(filePath)
+			if err != nil {
+				t.Fatalf("ReadMeta: %v", err)
+			}
+			if meta.Title != test.meta.Title || meta.Description != test.meta.Description || meta.Order != test.meta.Order || !slices.Equal(meta.Aliases, test.meta.Aliases) {
+				t.Errorf("ReadMeta = %+v, want %+v", meta, test.meta)
+			}
+
+			data, err := ParseFile(filePath, "tuvung")
+			if err != nil {
+				t.Fatalf("ParseFile: %v", err)
+			}
+			if !slices.Equal(data.Headers, test.headers) {
+				t.Errorf("headers = %q, want %q", data.Headers, test.headers)
+			}
+			if len(data.Rows) != test.rows {
+				t.Errorf("got %d rows, want %d", len(data.Rows), test.rows)
+			}
+		})
+	}
+}
@@ -1,14 +1,24 @@
 package parser
 
 import (
+	"bufio"
+	"crypto/sha1"
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 )
 
+// IDField is the column holding a row's stable identifier. It may be given
+// explicitly in the data file, otherwise AssignRowIDs fills it in.
+const IDField = "id"
+
 // ContentData represents the parsed data from a content file
 type ContentData struct {
 	// Source file path
@@ -17,14 +27,47 @@ type ContentData struct {
 	ContentType string
 	// ID of the content (from filename)
 	ContentID string
+	// Metadata of the file
+	Meta Meta
 	// Headers from the file (column names)
 	Headers []string
 	// Rows of data
 	Rows []map[string]string
+	// Reading passages shared by groups of rows
+	Passages []*Passage
+	// Rows grouped by passage, in the same order as Rows
+	Groups []Group
+	// Images referenced from the rows, by reference as written
+	Images map[string]*Image
+	// Tags of the whole file
+	Tags []string
+	// Tags of each row (including the file tags), by row ID
+	RowTags map[string][]string
 	// Raw data for custom processing
 	RawData interface{}
 }
 
+// Label returns the title of the content, or its file name when it has none
+func (d *ContentData) Label() string {
+	if d.Meta.Title != "" {
+		return d.Meta.Title
+	}
+	return path.Base(d.ContentID)
+}
+
+// Image is a published image referenced from a content file
+type Image struct {
+	// URL of the full-size image
+	URL string
+	// srcset of the resized copies, empty when there are none
+	SrcSet string
+	// Size of the full image in pixels, zero when unknown
+	Width  int
+	Height int
+	// Alternative text
+	Alt string
+}
+
 // ParseFile parses a file based on its extension and content type
 func ParseFile(filePath, contentType string) (*ContentData, error) {
 	ext := strings.ToLower(filepath.Ext(filePath))
@@ -36,14 +79,23 @@ func ParseFile(filePath, contentType string) (*ContentData, error) {
 		ContentID:   getContentID(filePath),
 	}
 
+	var err error
 	switch ext {
 	case ".csv":
-		return parseCSV(filePath, data)
+		data, err = parseCSV(filePath, data)
 	case ".json":
-		return parseJSON(filePath, data)
+		data, err = parseJSON(filePath, data)
 	default:
 		return nil, fmt.Errorf("unsupported file extension: %s", ext)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Meta, err = ReadMeta(filePath); err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
 // getContentID extracts content ID from file name
@@ -61,8 +113,14 @@ func parseCSV(filePath string, data *ContentData) (*ContentData, error) {
 	}
 	defer file.Close()
 
+	// Skip the metadata comments, read by ReadMeta
+	buffered := bufio.NewReader(file)
+	if _, err := ReadCommentHeader(buffered); err != nil {
+		return nil, err
+	}
+
 	// Create CSV reader
-	reader := csv.NewReader(file)
+	reader := csv.NewReader(buffered)
 	
 	// Read all records
 	records, err := reader.ReadAll()
@@ -158,6 +216,79 @@ func parseJSON(filePath string, data *ContentData) (*ContentData, error) {
 	return data, nil
 }
 
+// DuplicateRow is a row whose key fields are the same as those of an
+// earlier row, given the hash of the key fields followed by its occurrence
+type DuplicateRow struct {
+	// Row (1-based)
+	Row int
+	// Earlier row with the same key fields (1-based)
+	First int
+	// ID given to the row
+	ID string
+}
+
+// AssignRowIDs gives every row a stable ID under IDField so rows can be
+// linked to and tracked independently of their position in the file. An
+// explicit id column wins; otherwise the ID is a hash of the key fields
+// (all columns when keyFields is empty). Rows with the same key fields get
+// the hash followed by their occurrence ("-2", "-3") and are returned so
+// they can be reported. Key fields missing from the file and duplicate
+// explicit IDs are an error since they would make anchors and saved
+// progress ambiguous.
+func AssignRowIDs(data *ContentData, keyFields []string) ([]DuplicateRow, error) {
+	if len(keyFields) == 0 {
+		for _, header := range data.Headers {
+			if header != IDField {
+				keyFields = append(keyFields, header)
+			}
+		}
+		// JSON headers come from map iteration, so fix their order
+		sort.Strings(keyFields)
+	}
+	for _, field := range keyFields {
+		if !slices.Contains(data.Headers, field) {
+			return nil, fmt.Errorf("key field %q is not a column", field)
+		}
+	}
+
+	var duplicates []DuplicateRow
+	seen := make(map[string]int)
+	for i, row := range data.Rows {
+		id := strings.Join(strings.Fields(row[IDField]), "-")
+		if id == "" {
+			hash := hashRow(row, keyFields)
+			id = hash
+			for n := 2; ; n++ {
+				if _, taken := seen[id]; !taken {
+					break
+				}
+				id = fmt.Sprintf("%s-%d", hash, n)
+			}
+			if id != hash {
+				duplicates = append(duplicates, DuplicateRow{Row: i + 1, First: seen[hash] + 1, ID: id})
+			}
+		}
+
+		if first, ok := seen[id]; ok {
+			return nil, fmt.Errorf("duplicate row ID %q (rows %d and %d)", id, first+1, i+1)
+		}
+		seen[id] = i
+		row[IDField] = id
+	}
+
+	return duplicates, nil
+}
+
+// hashRow returns a short content hash of the key fields of a row
+func hashRow(row map[string]string, keyFields []string) string {
+	h := sha1.New()
+	for _, field := range keyFields {
+		h.Write([]byte(strings.TrimSpace(row[field])))
+		h.Write([]byte{0x1f})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:10]
+}
+
 // Parser interface for pluggable parsers
 type Parser interface {
 	Parse(filePath string) (*ContentData, error)
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestAssignRowIDs(t *testing.T) {
+	rows := func(values ...string) []map[string]string {
+		var rows []map[string]string
+		for i := 0; i < len(values); i += 2 {
+			rows = append(rows, map[string]string{"japanese": values[i], "meaning": values[i+1]})
+		}
+		return rows
+	}
+
+	t.Run("key fields", func(t *testing.T) {
+		data := &ContentData{Headers: []string{"japanese", "meaning"}, Rows: rows("食べる", "ăn", "飲む", "uống")}
+		if _, err := AssignRowIDs(data, []string{"japanese"}); err != nil {
+			t.Fatal(err)
+		}
+		id := data.Rows[0][IDField]
+
+		// Fixing a meaning keeps the ID
+		data = &ContentData{Headers: []string{"japanese", "meaning"}, Rows: rows("食べる", "ăn (cơm)", "飲む", "uống")}
+		if _, err := AssignRowIDs(data, []string{"japanese"}); err != nil {
+			t.Fatal(err)
+		}
+		if data.Rows[0][IDField] != id {
+			t.Errorf("ID changed from %q to %q", id, data.Rows[0][IDField])
+		}
+	})
+
+	t.Run("duplicate rows", func(t *testing.T) {
+		data := &ContentData{Headers: []string{"japanese", "meaning"}, Rows: rows("食べる", "ăn", "飲む", "uống", "食べる", "ăn")}
+		duplicates, err := AssignRowIDs(data, []string{"japanese"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := data.Rows[0][IDField] + "-2"
+		if len(duplicates) != 1 || duplicates[0] != (DuplicateRow{Row: 3, First: 1, ID: want}) {
+			t.Errorf("duplicates = %+v, want row 3 of row 1 as %q", duplicates, want)
+		}
+		if data.Rows[2][IDField] != want {
+			t.Errorf("ID = %q, want %q", data.Rows[2][IDField], want)
+		}
+	})
+
+	t.Run("missing key field", func(t *testing.T) {
+		data := &ContentData{Headers: []string{"japanese", "meaning"}, Rows: rows("食べる", "ăn")}
+		if _, err := AssignRowIDs(data, []string{"reading"}); err == nil {
+			t.Error("no error for a key field that is not a column")
+		}
+	})
+
+	t.Run("duplicate explicit IDs", func(t *testing.T) {
+		data := &ContentData{
+			Headers: []string{IDField, "japanese"},
+			Rows:    []map[string]string{{IDField: "a", "japanese": "食べる"}, {IDField: "a", "japanese": "飲む"}},
+		}
+		if _, err := AssignRowIDs(data, nil); err == nil {
+			t.Error("no error for duplicate explicit IDs")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PassagesSuffix marks the sidecar file holding the reading passages of the
+// content file with the same base name, e.g. doc1.passages.csv for doc1.csv.
+// Sidecar files have the columns id, title and text, and optionally audio
+// for listening passages.
+const PassagesSuffix = ".passages"
+
+// Passage is a reading text shared by a group of questions
+type Passage struct {
+	// ID referenced from the passage column of the questions
+	ID string
+	// Optional title shown above the text
+	Title string
+	// Text of the passage
+	Text string
+	// Optional recording, a media reference like those of audio fields
+	Audio string
+}
+
+// Group is a run of rows rendered together, under a passage when they
+// belong to one
+type Group struct {
+	// Passage of the group, nil for rows without a passage
+	Passage *Passage
+	// Index of the group's first row in ContentData.Rows
+	Start int
+	// Rows of the group
+	Rows []map[string]string
+}
+
+// IsPassageFile reports whether a data file is a passage sidecar rather
+// than content of its own
+func IsPassageFile(filePath string) bool {
+	baseName := filepath.Base(filePath)
+	return strings.HasSuffix(strings.TrimSuffix(baseName, filepath.Ext(baseName)), PassagesSuffix)
+}
+
+// AttachPassages loads the passage sidecar of a content file, if any, and
+// groups the rows by the passage named in passageField. Rows of a passage
+// are gathered where the passage is first used and Rows is reordered to
+// match the groups. A row naming an unknown passage is an error.
+func AttachPassages(data *ContentData, passageField string) error {
+	passages, err := loadPassages(data)
+	if err != nil {
+		return err
+	}
+	data.Passages = passages
+
+	return GroupRows(data, passageField)
+}
+
+// GroupRows (re)builds the groups of data from the passage column and
+// reorders Rows to match. Consecutive rows without a passage share a group.
+func GroupRows(data *ContentData, passageField string) error {
+	byID := make(map[string]*Passage, len(data.Passages))
+	for _, passage := range data.Passages {
+		byID[passage.ID] = passage
+	}
+
+	var groups []*Group
+	passageGroups := make(map[string]*Group)
+	for i, row := range data.Rows {
+		id := strings.TrimSpace(row[passageField])
+		if id == "" {
+			last := len(groups) - 1
+			if last < 0 || groups[last].Passage != nil {
+				groups = append(groups, &Group{})
+			}
+			groups[len(groups)-1].Rows = append(groups[len(groups)-1].Rows, row)
+			continue
+		}
+
+		group, ok := passageGroups[id]
+		if !ok {
+			passage, ok := byID[id]
+			if !ok {
+				return fmt.Errorf("unknown passage %q in %s (row %d)", id, data.SourcePath, i+1)
+			}
+			group = &Group{Passage: passage}
+			passageGroups[id] = group
+			groups = append(groups, group)
+		}
+		group.Rows = append(group.Rows, row)
+	}
+
+	data.Groups = make([]Group, 0, len(groups))
+	data.Rows = make([]map[string]string, 0, len(data.Rows))
+	for _, group := range groups {
+		group.Start = len(data.Rows)
+		data.Rows = append(data.Rows, group.Rows...)
+		data.Groups = append(data.Groups, *group)
+	}
+
+	return nil
+}
+
+// loadPassages reads the passage sidecar of a content file, returning no
+// passages when there is none
+func loadPassages(data *ContentData) ([]*Passage, error) {
+	base := strings.TrimSuffix(data.SourcePath, filepath.Ext(data.SourcePath)) + PassagesSuffix
+	for _, ext := range []string{".csv", ".json"} {
+		path := base + ext
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		sidecar, err := ParseFile(path, data.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse passages %s: %w", path, err)
+		}
+
+		passages := make([]*Passage, 0, len(sidecar.Rows))
+		seen := make(map[string]bool)
+		for i, row := range sidecar.Rows {
+			id := strings.TrimSpace(row["id"])
+			if id == "" {
+				return nil, fmt.Errorf("passage without id in %s (row %d)", path, i+1)
+			}
+			if seen[id] {
+				return nil, fmt.Errorf("duplicate passage %q in %s", id, path)
+			}
+			seen[id] = true
+			passages = append(passages, &Passage{
+				ID:    id,
+				Title: strings.TrimSpace(row["title"]),
+				Text:  strings.TrimSpace(row["text"]),
+				Audio: strings.TrimSpace(row["audio"]),
+			})
+		}
+		return passages, nil
+	}
+
+	return nil, nil
+}
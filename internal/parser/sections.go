@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// IndexID is the content ID reserved for the index page of a content type or
+// section, so data files cannot be named index
+const IndexID = "index"
+
+// ContentFiles lists the data files of a content type directory, including
+// those in section subdirectories, in path order. Passage sidecars and draft
+// sections are left out.
+func ContentFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path == dir {
+				return nil
+			}
+			meta, err := ReadMeta(path)
+			if err != nil {
+				return err
+			}
+			if meta.Draft {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := filepath.Ext(entry.Name())
+		if (ext == ".csv" || ext == ".json") && !IsPassageFile(entry.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// ContentIDOf returns the content ID of a data file within its content type
+// directory: the file name without extension, prefixed with its sections,
+// e.g. n1/bai1 for tuvung/n1/bai1.csv
+func ContentIDOf(dir, filePath string) string {
+	rel, err := filepath.Rel(dir, filePath)
+	if err != nil {
+		return getContentID(filePath)
+	}
+	return filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"strings"
+
+	"captoc/internal/slug"
+)
+
+// SplitTags splits a tags cell on commas, semicolons and the Japanese
+// comma, dropping empty tags
+func SplitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '、' || r == '，'
+	}) {
+		if tag = strings.Join(strings.Fields(tag), " "); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// TagSlug returns the URL form of a tag ("Kính ngữ" → "kinh-ngu", "N2" →
+// "n2"), see slug.Make
+func TagSlug(tag string) string {
+	return slug.Make(tag)
+}
+
+// AssignTags sets the file tags of data and the tags of each row: the file
+// tags followed by those in the row's tag fields. Tags with the same slug
+// are kept once, as first written.
+func AssignTags(data *ContentData, fileTags []string, fields ...string) {
+	data.Tags = uniqueTags(fileTags)
+	data.RowTags = make(map[string][]string, len(data.Rows))
+	for _, row := range data.Rows {
+		tags := append([]string{}, data.Tags...)
+		for _, field := range fields {
+			tags = append(tags, SplitTags(row[field])...)
+		}
+		if tags = uniqueTags(tags); len(tags) > 0 {
+			data.RowTags[row[IDField]] = tags
+		}
+	}
+}
+
+// AllTags returns the tags of the file and its rows, in order of first use
+func (d *ContentData) AllTags() []string {
+	tags := append([]string{}, d.Tags...)
+	for _, row := range d.Rows {
+		tags = append(tags, d.RowTags[row[IDField]]...)
+	}
+	return uniqueTags(tags)
+}
+
+// uniqueTags drops tags whose slug is empty or already seen
+func uniqueTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		slug := TagSlug(tag)
+		if slug != "" && !seen[slug] {
+			seen[slug] = true
+			result = append(result, strings.TrimSpace(tag))
+		}
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+// Package password encrypts generated pages and data files with a
+// password, for content that must stay private on a public site. The
+// browser derives the same key with PBKDF2 and decrypts with AES-GCM.
+package password
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Iterations is the PBKDF2 iteration count used for new envelopes
+const Iterations = 200000
+
+// Envelope is encrypted content with what the browser needs to decrypt it
+// given the password
+type Envelope struct {
+	// Base64 PBKDF2 salt
+	Salt string `json:"salt"`
+	// PBKDF2 iteration count (SHA-256)
+	Iterations int `json:"iterations"`
+	// Base64 AES-GCM nonce followed by the ciphertext
+	Data string `json:"data"`
+}
+
+// Encrypt encrypts plaintext with a key derived from password. Salt and
+// nonce are random, so the output differs between builds even when the
+// content does not; reusing them would leak what changed between versions.
+func Encrypt(password string, plaintext []byte) (*Envelope, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Iterations: Iterations,
+		Data:       base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)),
+	}, nil
+}
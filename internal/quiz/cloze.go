@@ -0,0 +1,146 @@
+package quiz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"captoc/internal/config"
+	"captoc/internal/japanese"
+	"captoc/internal/parser"
+)
+
+// clozeBlank replaces the target word in a cloze question
+const clozeBlank = "＿＿＿"
+
+// clozeQuiz generates typed-answer questions that blank each item's word
+// out of its example sentence. Items without an example, or whose word
+// cannot be found in it, are left out.
+func clozeQuiz(contentType string, contentTypeConfig config.ContentTypeConfig, source *parser.ContentData, items []vocabItem) *parser.ContentData {
+	numberField := contentTypeConfig.FieldName(config.RoleNumber)
+	questionField := contentTypeConfig.FieldName(config.RoleQuestion)
+	answerField := contentTypeConfig.FieldName(config.RoleAnswer)
+	typeField := contentTypeConfig.FieldName(config.RoleQuestionType)
+	explanationField := contentTypeConfig.FieldName(config.RoleExplanation)
+
+	data := &parser.ContentData{
+		SourcePath:  source.SourcePath,
+		ContentType: contentType,
+		ContentID:   contentTypeConfig.Derive.ContentID(source.ContentID, config.DeriveCloze),
+		Meta:        parser.Meta{Title: contentTypeConfig.Derive.Title(source.Meta.Title, config.DeriveCloze)},
+		Headers:     []string{parser.IDField, numberField, questionField, answerField, typeField, explanationField},
+	}
+
+	for _, item := range items {
+		start, end, accepted := clozeTarget(item)
+		if accepted == nil {
+			continue
+		}
+
+		question := item.example[:start] + clozeBlank + item.example[end:]
+		if item.meaning != "" {
+			question = fmt.Sprintf("%s (%s)", question, item.meaning)
+		}
+
+		data.Rows = append(data.Rows, map[string]string{
+			parser.IDField:   item.id,
+			numberField:      strconv.Itoa(len(data.Rows) + 1),
+			questionField:    question,
+			answerField:      FormatOptions(accepted),
+			typeField:        TypeText,
+			explanationField: item.example,
+		})
+	}
+
+	return data
+}
+
+// clozeTarget finds the item's word in its example sentence and returns
+// the byte range to blank with the answers accepted for it. The word is
+// looked up as written and in kana; conjugated forms are found through
+// the stem left once the ending is dropped (食べる → 食べ in 食べました),
+// keeping the inflection visible after the blank.
+func clozeTarget(item vocabItem) (start, end int, accepted []string) {
+	if item.example == "" {
+		return 0, 0, nil
+	}
+
+	// Grammar patterns are often written with a leading wave dash
+	word := strings.TrimLeft(item.word, "〜～~")
+	reading := strings.TrimLeft(item.reading, "〜～~")
+
+	type form struct {
+		text     string
+		accepted []string
+	}
+	var forms []form
+	add := func(text string, accepted ...string) {
+		if text != "" {
+			forms = append(forms, form{text, unique(append([]string{text}, accepted...))})
+		}
+	}
+
+	add(word, reading)
+	add(reading, word)
+
+	// Stems of inflecting words, with the kana reading of the kanji stem
+	// taken as the reading minus the same okurigana
+	if stem, okurigana := splitOkurigana(word); stem != "" && okurigana > 0 {
+		readingStem := ""
+		// A one-kana stem would match almost any sentence
+		if n := utf8.RuneCountInString(reading); n-okurigana >= 2 && reading != word {
+			readingStem = string([]rune(reading)[:n-okurigana])
+		}
+		add(stem, readingStem)
+		add(readingStem, stem)
+	}
+
+	// The longest form found wins, so a full word beats its stem
+	best := -1
+	for i, f := range forms {
+		index := strings.Index(item.example, f.text)
+		if index < 0 {
+			continue
+		}
+		if best < 0 || len(f.text) > len(forms[best].text) {
+			best, start = i, index
+		}
+	}
+	if best < 0 {
+		return 0, 0, nil
+	}
+	return start, start + len(forms[best].text), forms[best].accepted
+}
+
+// splitOkurigana drops the inflecting ending of a word written with
+// kanji: する of suru verbs, otherwise the final hiragana (食べる → 食べ,
+// 飲む → 飲, 高い → 高). It returns the stem and the number of kana
+// dropped; words without kanji or kana ending have no stem.
+func splitOkurigana(word string) (string, int) {
+	if !japanese.ContainsKanji(word) {
+		return "", 0
+	}
+	if stem, ok := strings.CutSuffix(word, "する"); ok && stem != "" {
+		return stem, 2
+	}
+	runes := []rune(word)
+	last := len(runes) - 1
+	if last < 1 || !japanese.IsHiragana(runes[last]) {
+		return "", 0
+	}
+	return string(runes[:last]), 1
+}
+
+// unique drops empty and repeated values, keeping the first of each
+func unique(values []string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package quiz
+
+import (
+	"html/template"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/markdown"
+)
+
+// Explanation display modes
+const (
+	ExplainOnCheck  = "check"
+	ExplainOnReveal = "reveal"
+	ExplainNever    = "never"
+)
+
+// ExplanationMode returns when the explanations of a content type are
+// shown, defaulting to after checking an answer
+func ExplanationMode(contentTypeConfig config.ContentTypeConfig) string {
+	switch contentTypeConfig.ShowExplanation {
+	case ExplainOnReveal, ExplainNever:
+		return contentTypeConfig.ShowExplanation
+	}
+	return ExplainOnCheck
+}
+
+// Explanation renders the explanation of a question, as Markdown when the
+// content type asks for it and as plain text otherwise
+func Explanation(contentTypeConfig config.ContentTypeConfig, text string) template.HTML {
+	text = strings.TrimSpace(text)
+	if contentTypeConfig.ExplanationMarkdown {
+		return markdown.Render(text)
+	}
+	return template.HTML("<p>" + strings.ReplaceAll(template.HTMLEscapeString(text), "\n", "<br>") + "</p>")
+}
+
+// Reference is a link from a question to related material
+type Reference struct {
+	// Text of the link
+	Label string
+	// Target as understood by config.Config.RefURL
+	Target string
+}
+
+// ParseReferences parses the references column of a question: a list of
+// targets, each optionally labeled as "label | target"
+func ParseReferences(s string) []Reference {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	var references []Reference
+	for _, item := range ParseOptions(s) {
+		label, target, ok := strings.Cut(item, "|")
+		if !ok {
+			target = label
+		}
+		label, target = strings.TrimSpace(label), strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		references = append(references, Reference{Label: label, Target: target})
+	}
+	return references
+}
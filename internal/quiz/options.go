@@ -0,0 +1,132 @@
+package quiz
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"captoc/internal/japanese"
+)
+
+// listQuotes maps the quotes that may open an option in a list to the quote
+// closing it, with the full-width and curly quotes typed with a Japanese IME
+var listQuotes = map[rune]rune{
+	'\'': '\'', '"': '"', '＇': '＇', '＂': '＂', '‘': '’', '“': '”',
+}
+
+// ParseOptions parses a string representation of an array into a slice of strings
+func ParseOptions(s string) []string {
+	s = strings.TrimSpace(s)
+
+	// Try to parse as JSON first
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		var options []string
+		if err := json.Unmarshal([]byte(s), &options); err == nil {
+			return options
+		}
+
+		// Replace single quotes with double quotes for JSON parsing
+		if err := json.Unmarshal([]byte(strings.ReplaceAll(s, "'", "\"")), &options); err == nil {
+			return options
+		}
+	}
+
+	// Lists written with full-width brackets, quotes or commas
+	if options, ok := scanList(s); ok {
+		return options
+	}
+
+	// If JSON parsing fails, try simple comma separation
+	parts := strings.Split(s, ",")
+	options := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			options = append(options, p)
+		}
+	}
+
+	return options
+}
+
+// scanList reads a bracketed option list whose brackets, quotes and commas
+// may be ASCII or full-width (［“はい，そうです”，“いいえ”］). Only delimiters
+// are read that way: the text of a quoted option is kept as written. It
+// reports false when s is not such a list.
+func scanList(s string) ([]string, bool) {
+	inner, ok := cutAny(s, strings.CutPrefix, "[", "［")
+	if !ok {
+		return nil, false
+	}
+	if inner, ok = cutAny(inner, strings.CutSuffix, "]", "］"); !ok {
+		return nil, false
+	}
+
+	options := []string{}
+	rest := strings.TrimSpace(inner)
+	for rest != "" {
+		open, size := utf8.DecodeRuneInString(rest)
+		if closing, quoted := listQuotes[open]; quoted {
+			// The closing quote is the one followed by a separator or the
+			// end of the list, so "don’t" keeps its apostrophe
+			body := rest[size:]
+			found := false
+			for i, r := range body {
+				if r != closing {
+					continue
+				}
+				after := strings.TrimSpace(body[i+utf8.RuneLen(r):])
+				if after == "" || strings.HasPrefix(after, ",") || strings.HasPrefix(after, "，") {
+					options = append(options, body[:i])
+					rest, found = after, true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		} else {
+			end := strings.IndexAny(rest, ",，")
+			if end < 0 {
+				end = len(rest)
+			}
+			if option := strings.TrimSpace(rest[:end]); option != "" {
+				options = append(options, option)
+			}
+			rest = rest[end:]
+		}
+
+		if rest, ok = cutAny(rest, strings.CutPrefix, ",", "，"); ok {
+			rest = strings.TrimSpace(rest)
+		}
+	}
+	return options, true
+}
+
+// cutAny cuts the first of the given delimiters that cut succeeds with
+func cutAny(s string, cut func(s, delim string) (string, bool), delims ...string) (string, bool) {
+	for _, delim := range delims {
+		if rest, ok := cut(s, delim); ok {
+			return rest, true
+		}
+	}
+	return s, false
+}
+
+// FormatOptions encodes options in the JSON list form read by ParseOptions
+func FormatOptions(options []string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(options); err != nil {
+		return strings.Join(options, ", ")
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// SameAnswer reports whether two answers match once surrounding space and
+// full-width/half-width differences are ignored
+func SameAnswer(a, b string) bool {
+	return strings.TrimSpace(japanese.Normalize(a)) == strings.TrimSpace(japanese.Normalize(b))
+}
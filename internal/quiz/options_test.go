@@ -0,0 +1,66 @@
+package quiz
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`["a", "b"]`, []string{"a", "b"}},
+		{`['a', 'b']`, []string{"a", "b"}},
+		{`[]`, []string{}},
+		{`a, b ,c`, []string{"a", "b", "c"}},
+		// Quotes and commas inside an option are kept
+		{`['“hello”', 'b']`, []string{"“hello”", "b"}},
+		{`['don’t','b']`, []string{"don’t", "b"}},
+		{`["はい，そうです", "いいえ"]`, []string{"はい，そうです", "いいえ"}},
+		{`はい，そうです`, []string{"はい，そうです"}},
+		// Full-width and curly delimiters
+		{`［'a'，'b'］`, []string{"a", "b"}},
+		{`［“はい，そうです”，“いいえ”］`, []string{"はい，そうです", "いいえ"}},
+		{`[“hello”, “world”]`, []string{"hello", "world"}},
+		{`[‘don’t’, ‘b’]`, []string{"don’t", "b"}},
+		{`［＂a＂，＂b＂］`, []string{"a", "b"}},
+		{`［た，だ］`, []string{"た", "だ"}},
+		{`［］`, []string{}},
+	}
+	for _, tt := range tests {
+		if got := ParseOptions(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseOptions(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatOptionsRoundTrip(t *testing.T) {
+	for _, options := range [][]string{
+		{"a", "b"},
+		{"“hello”", "don’t", "はい，そうです"},
+		{`say "hi"`, "<b>"},
+	} {
+		if got := ParseOptions(FormatOptions(options)); !slices.Equal(got, options) {
+			t.Errorf("ParseOptions(FormatOptions(%q)) = %q", options, got)
+		}
+	}
+}
+
+func TestSameAnswer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"たべる", " たべる ", true},
+		{"ＡＢＣ１２", "ABC12", true},
+		{"ｶﾀｶﾅ", "カタカナ", true},
+		{"はい，そうです", "はい,そうです", true},
+		{"たべる", "のむ", false},
+	}
+	for _, tt := range tests {
+		if got := SameAnswer(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameAnswer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package quiz
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+
+	"captoc/internal/config"
+)
+
+// Answer protection modes. Protected pages carry each question's answers
+// encrypted instead of as plain markup, and the page decrypts them only
+// when the question is checked. The key is in the page, so this keeps
+// answers out of view-source rather than away from a determined reader.
+const (
+	ProtectNone = "none"
+	ProtectExam = "exam"
+	ProtectAll  = "all"
+)
+
+// Protects reports whether answers are protected on a page of a content
+// type; exam tells whether the page is an exam page
+func Protects(contentTypeConfig config.ContentTypeConfig, exam bool) bool {
+	switch contentTypeConfig.AnswerProtection {
+	case ProtectAll:
+		return true
+	case ProtectExam:
+		return exam
+	}
+	return false
+}
+
+// PageKey returns the base64 encoded answer key of a page. It is derived
+// from the page path so protected pages stay identical across builds.
+func PageKey(pagePath string) string {
+	sum := sha256.Sum256([]byte("captoc answers\x00" + pagePath))
+	return base64.StdEncoding.EncodeToString(sum[:16])
+}
+
+// AnswerData is what a protected question needs to be checked in the
+// browser, in place of the markup left out of the page
+type AnswerData struct {
+	// Indices of the correct options (single and multiple choice)
+	Correct []int `json:"correct,omitempty"`
+	// Accepted answers (text)
+	Accepted []string `json:"accepted,omitempty"`
+	// Pieces in their correct order (ordering)
+	Order []string `json:"order,omitempty"`
+	// Right item of each pair, in pair order (matching)
+	Matches []string `json:"matches,omitempty"`
+	// HTML of the answer result block
+	Result template.HTML `json:"result"`
+}
+
+// NewAnswerData collects the answers of a question along with the HTML of
+// its answer result block
+func NewAnswerData(questionType string, options []string, answer string, result template.HTML) AnswerData {
+	data := AnswerData{Result: result}
+
+	switch questionType {
+	case TypeText:
+		data.Accepted = Answers(answer)
+	case TypeOrdering:
+		data.Order = Answers(answer)
+	case TypeMatching:
+		for _, pair := range ParsePairs(options) {
+			data.Matches = append(data.Matches, pair.Right)
+		}
+	default:
+		answers := []string{answer}
+		if questionType == TypeMultiple {
+			answers = Answers(answer)
+		}
+		for i, option := range options {
+			if IsAnswer(option, answers) {
+				data.Correct = append(data.Correct, i)
+			}
+		}
+	}
+
+	return data
+}
+
+// SealAnswers encrypts the answers of a question with a page key using
+// AES-GCM. The nonce comes from the row ID, which is unique within a page,
+// and is prepended to the base64 encoded result.
+func SealAnswers(pageKey string, data AnswerData, rowID string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(pageKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid answer key: %w", err)
+	}
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := Seal(key, plaintext, []byte(rowID))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Seal encrypts plaintext with AES-GCM under key, using a nonce derived
+// from nonceSeed, and returns the nonce followed by the ciphertext
+func Seal(key, plaintext, nonceSeed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(nonceSeed)
+	nonce := sum[:gcm.NonceSize()]
+	return gcm.Seal(append([]byte{}, nonce...), nonce, plaintext, nil), nil
+}
@@ -0,0 +1,176 @@
+package quiz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/japanese"
+)
+
+// Question types. The answer column holds the single correct option, a list
+// of correct options (multiple), the accepted answers (text) or the pieces in
+// their correct order (ordering). Matching questions list "left = right"
+// pairs in the options column and leave the answer column empty.
+const (
+	TypeSingle   = "single"
+	TypeMultiple = "multiple"
+	TypeText     = "text"
+	TypeOrdering = "ordering"
+	TypeMatching = "matching"
+)
+
+// typeAliases maps the values accepted in the question type column to types
+var typeAliases = map[string]string{
+	TypeSingle:     TypeSingle,
+	TypeMultiple:   TypeMultiple,
+	TypeText:       TypeText,
+	TypeOrdering:   TypeOrdering,
+	TypeMatching:   TypeMatching,
+	"một đáp án":   TypeSingle,
+	"nhiều đáp án": TypeMultiple,
+	"điền từ":      TypeText,
+	"sắp xếp":      TypeOrdering,
+	"ghép cặp":     TypeMatching,
+}
+
+// QuestionType returns the type of the question in a row, falling back to
+// the content type's default and then to single choice
+func QuestionType(contentTypeConfig config.ContentTypeConfig, row map[string]string) string {
+	value := row[contentTypeConfig.FieldName(config.RoleQuestionType)]
+	if value == "" {
+		value = contentTypeConfig.QuestionType
+	}
+	if questionType, ok := typeAliases[strings.ToLower(strings.TrimSpace(value))]; ok {
+		return questionType
+	}
+	return TypeSingle
+}
+
+// Answers parses the answer column of a question into its accepted answers
+func Answers(s string) []string {
+	return ParseOptions(s)
+}
+
+// IsAnswer reports whether an option is one of the correct answers
+func IsAnswer(option string, answers []string) bool {
+	for _, answer := range answers {
+		if SameAnswer(option, answer) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pair is a left and right item of a matching question
+type Pair struct {
+	Left  string
+	Right string
+}
+
+// ParsePairs splits the "left = right" options of a matching question
+func ParsePairs(options []string) []Pair {
+	pairs := make([]Pair, 0, len(options))
+	for _, option := range options {
+		left, right, _ := strings.Cut(strings.ReplaceAll(option, "＝", "="), "=")
+		pairs = append(pairs, Pair{Left: strings.TrimSpace(left), Right: strings.TrimSpace(right)})
+	}
+	return pairs
+}
+
+// MatchChoices returns the right items of a matching question in sorted
+// order, so the order of the choices does not give the pairs away
+func MatchChoices(pairs []Pair) []string {
+	choices := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		choices = append(choices, pair.Right)
+	}
+	sort.Strings(choices)
+	return choices
+}
+
+// Letter returns the option letter for a zero-based index (0 → A)
+func Letter(i int) string {
+	if i < 0 || i >= 26 {
+		return fmt.Sprintf("%d", i+1)
+	}
+	return string(rune('A' + i))
+}
+
+// KeyLetters returns the answer of a question as option letters, the way it
+// is written on the answer key of a printed test form
+func KeyLetters(questionType string, options []string, answer string) string {
+	switch questionType {
+	case TypeText:
+		return ""
+	case TypeOrdering:
+		var letters []string
+		for _, piece := range Answers(answer) {
+			letters = append(letters, optionLetter(options, piece))
+		}
+		return strings.Join(letters, " → ")
+	case TypeMatching:
+		pairs := ParsePairs(options)
+		choices := MatchChoices(pairs)
+		letters := make([]string, 0, len(pairs))
+		for i, pair := range pairs {
+			letters = append(letters, fmt.Sprintf("%d-%s", i+1, optionLetter(choices, pair.Right)))
+		}
+		return strings.Join(letters, ", ")
+	}
+
+	answers := Answers(answer)
+	if questionType == TypeSingle {
+		answers = []string{answer}
+	}
+	var letters []string
+	for i, option := range options {
+		if IsAnswer(option, answers) {
+			letters = append(letters, Letter(i))
+		}
+	}
+	return strings.Join(letters, ", ")
+}
+
+// AnswerText returns the correct answer of a question as shown after checking
+func AnswerText(questionType string, options []string, answer string) string {
+	switch questionType {
+	case TypeMultiple:
+		return strings.Join(Answers(answer), ", ")
+	case TypeText:
+		return strings.Join(Answers(answer), " / ")
+	case TypeOrdering:
+		return joinSentence(Answers(answer))
+	case TypeMatching:
+		var lines []string
+		for _, pair := range ParsePairs(options) {
+			lines = append(lines, pair.Left+" → "+pair.Right)
+		}
+		return strings.Join(lines, "; ")
+	}
+	return answer
+}
+
+// optionLetter returns the letter of the option matching value, or "?"
+func optionLetter(options []string, value string) string {
+	for i, option := range options {
+		if SameAnswer(option, value) {
+			return Letter(i)
+		}
+	}
+	return "?"
+}
+
+// joinSentence joins the pieces of an ordering question, without spaces
+// when they are Japanese
+func joinSentence(pieces []string) string {
+	for _, piece := range pieces {
+		for _, r := range piece {
+			if japanese.IsKana(r) || japanese.IsKanji(r) {
+				return strings.Join(pieces, "")
+			}
+		}
+	}
+	return strings.Join(pieces, " ")
+}
@@ -0,0 +1,112 @@
+package quiz
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/parser"
+)
+
+// Variant is a reproducibly shuffled version of a quiz file, printed as a
+// separate test form
+type Variant struct {
+	// Variant number, starting at 1
+	Number int
+	// Seed the variant was shuffled with
+	Seed int64
+	// Shuffled content
+	Content *parser.ContentData
+}
+
+// Kind returns the page kind of the variant, used in its URL
+func (v *Variant) Kind() string {
+	return fmt.Sprintf("v%d", v.Number)
+}
+
+// KeyKind returns the page kind of the variant's answer key
+func (v *Variant) KeyKind() string {
+	return v.Kind() + ".key"
+}
+
+// BaseSeed returns the configured variant seed of a content type, or a seed
+// derived from its name so forms stay the same across builds
+func BaseSeed(contentType string, contentTypeConfig config.ContentTypeConfig) int64 {
+	if contentTypeConfig.VariantSeed != 0 {
+		return contentTypeConfig.VariantSeed
+	}
+	return HashSeed(contentType)
+}
+
+// MakeVariants generates the configured number of shuffled variants of a quiz file
+func MakeVariants(data *parser.ContentData, contentTypeConfig config.ContentTypeConfig, baseSeed int64) []*Variant {
+	variants := make([]*Variant, 0, contentTypeConfig.Variants)
+	for number := 1; number <= contentTypeConfig.Variants; number++ {
+		seed := HashSeed(strconv.FormatInt(baseSeed, 10), data.ContentID, strconv.Itoa(number))
+		variants = append(variants, &Variant{
+			Number:  number,
+			Seed:    seed,
+			Content: Shuffle(data, contentTypeConfig, rand.New(rand.NewSource(seed))),
+		})
+	}
+	return variants
+}
+
+// Shuffle returns a copy of data with the questions and the options of each
+// question in random order. Questions on a reading passage stay together
+// under it. Question numbers are rewritten to match the new order; row IDs
+// are kept.
+func Shuffle(data *parser.ContentData, contentTypeConfig config.ContentTypeConfig, rng *rand.Rand) *parser.ContentData {
+	optionsField := contentTypeConfig.FieldName(config.RoleOptions)
+	numberField := contentTypeConfig.FieldName(config.RoleNumber)
+
+	shuffled := *data
+	shuffled.Rows = make([]map[string]string, len(data.Rows))
+	for i, index := range rng.Perm(len(data.Rows)) {
+		row := copyRow(data.Rows[index])
+
+		// Questions without options (typed answers) keep the column as it is
+		if value, ok := row[optionsField]; ok && strings.TrimSpace(value) != "" {
+			options := ParseOptions(value)
+			rng.Shuffle(len(options), func(a, b int) {
+				options[a], options[b] = options[b], options[a]
+			})
+			row[optionsField] = FormatOptions(options)
+		}
+
+		shuffled.Rows[i] = row
+	}
+
+	// Regrouping cannot fail: the passages were checked when the file was parsed
+	_ = parser.GroupRows(&shuffled, contentTypeConfig.FieldName(config.RolePassage))
+
+	for i, row := range shuffled.Rows {
+		if _, ok := row[numberField]; ok {
+			row[numberField] = strconv.Itoa(i + 1)
+		}
+	}
+
+	return &shuffled
+}
+
+// copyRow returns a copy of a row so shuffling leaves the source untouched
+func copyRow(row map[string]string) map[string]string {
+	copied := make(map[string]string, len(row))
+	for key, value := range row {
+		copied[key] = value
+	}
+	return copied
+}
+
+// HashSeed derives a seed from the given parts, the same in every build
+func HashSeed(parts ...string) int64 {
+	h := fnv.New64a()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return int64(h.Sum64() & 0x7fffffffffffffff)
+}
@@ -0,0 +1,206 @@
+package quiz
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"captoc/internal/config"
+	"captoc/internal/japanese"
+	"captoc/internal/parser"
+)
+
+// defaultVocabOptions is the number of options of a generated question when
+// the content type does not set one
+const defaultVocabOptions = 4
+
+// vocabItem is a vocabulary row reduced to what question generation needs
+type vocabItem struct {
+	id        string
+	word      string
+	reading   string
+	meaning   string
+	wordClass string
+	example   string
+}
+
+// VocabQuizzes generates the quiz files of a derived content type from a
+// vocabulary or grammar file, one per configured direction. Rows
+// missing the fields a direction needs are left out of it. Distractors are
+// other rows of the same file, preferring the same word class and a
+// similar length, and are chosen reproducibly so pages stay the same
+// across builds.
+func VocabQuizzes(cfg *config.Config, contentType string, source *parser.ContentData) ([]*parser.ContentData, error) {
+	contentTypeConfig := cfg.ContentTypes[contentType]
+	sourceConfig := cfg.ContentTypes[source.ContentType]
+	derive := contentTypeConfig.Derive
+
+	choices := derive.Options
+	if choices <= 0 {
+		choices = defaultVocabOptions
+	}
+	if choices < 2 {
+		return nil, fmt.Errorf("content type %s: options must be at least 2", contentType)
+	}
+
+	items := make([]vocabItem, 0, len(source.Rows))
+	for _, row := range source.Rows {
+		items = append(items, vocabItem{
+			id:        row[parser.IDField],
+			word:      strings.TrimSpace(row[sourceConfig.FieldName(config.RoleWord)]),
+			reading:   strings.TrimSpace(row[sourceConfig.FieldName(config.RoleReading)]),
+			meaning:   strings.TrimSpace(row[sourceConfig.FieldName(config.RoleMeaning)]),
+			wordClass: strings.TrimSpace(row[sourceConfig.FieldName(config.RoleWordClass)]),
+			example:   strings.TrimSpace(row[sourceConfig.FieldName(config.RoleExample)]),
+		})
+	}
+
+	numberField := contentTypeConfig.FieldName(config.RoleNumber)
+	questionField := contentTypeConfig.FieldName(config.RoleQuestion)
+	optionsField := contentTypeConfig.FieldName(config.RoleOptions)
+	answerField := contentTypeConfig.FieldName(config.RoleAnswer)
+
+	var quizzes []*parser.ContentData
+	for _, mode := range derive.ModeList() {
+		if mode == config.DeriveCloze {
+			quizzes = append(quizzes, clozeQuiz(contentType, contentTypeConfig, source, items))
+			continue
+		}
+
+		question, answer, err := vocabDirection(mode)
+		if err != nil {
+			return nil, fmt.Errorf("content type %s: %w", contentType, err)
+		}
+
+		data := &parser.ContentData{
+			SourcePath:  source.SourcePath,
+			ContentType: contentType,
+			ContentID:   derive.ContentID(source.ContentID, mode),
+			Meta:        parser.Meta{Title: derive.Title(source.Meta.Title, mode)},
+			Headers:     []string{parser.IDField, numberField, questionField, optionsField, answerField},
+		}
+
+		for _, item := range items {
+			prompt, correct := question(item), answer(item)
+			if prompt == "" || correct == "" {
+				continue
+			}
+
+			rng := rand.New(rand.NewSource(HashSeed(contentType, source.ContentID, mode, item.id)))
+			options := append(distractors(items, item, answer, choices-1, rng), correct)
+			if len(options) < 2 {
+				continue
+			}
+			rng.Shuffle(len(options), func(a, b int) {
+				options[a], options[b] = options[b], options[a]
+			})
+
+			data.Rows = append(data.Rows, map[string]string{
+				parser.IDField: item.id,
+				numberField:    strconv.Itoa(len(data.Rows) + 1),
+				questionField:  prompt,
+				optionsField:   FormatOptions(options),
+				answerField:    correct,
+			})
+		}
+
+		quizzes = append(quizzes, data)
+	}
+
+	return quizzes, nil
+}
+
+// vocabDirection returns how a direction builds the question and the
+// answer of a vocabulary item; an empty result skips the item
+func vocabDirection(mode string) (question, answer func(vocabItem) string, err error) {
+	switch mode {
+	case config.DeriveMeaning:
+		question = func(item vocabItem) string {
+			if item.word == "" {
+				return ""
+			}
+			if item.reading != "" && item.reading != item.word {
+				return fmt.Sprintf("「%s」(%s) nghĩa là gì?", item.word, item.reading)
+			}
+			return fmt.Sprintf("「%s」 nghĩa là gì?", item.word)
+		}
+		answer = func(item vocabItem) string { return item.meaning }
+	case config.DeriveWord:
+		question = func(item vocabItem) string {
+			if item.meaning == "" {
+				return ""
+			}
+			return fmt.Sprintf("Từ nào có nghĩa là “%s”?", item.meaning)
+		}
+		answer = func(item vocabItem) string { return item.word }
+	case config.DeriveKanji:
+		question = func(item vocabItem) string {
+			if item.reading == "" || !japanese.ContainsKanji(item.word) {
+				return ""
+			}
+			return fmt.Sprintf("Chữ Hán của 「%s」 là gì?", item.reading)
+		}
+		answer = func(item vocabItem) string {
+			if !japanese.ContainsKanji(item.word) {
+				return ""
+			}
+			return item.word
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown derive mode %q", mode)
+	}
+	return question, answer, nil
+}
+
+// distractors picks up to count wrong options for item from the other
+// items of the file. Candidates of the same word class come first, then
+// those closest in length; ties are broken randomly.
+func distractors(items []vocabItem, item vocabItem, answer func(vocabItem) string, count int, rng *rand.Rand) []string {
+	correct := answer(item)
+
+	type candidate struct {
+		value string
+		score int
+	}
+	var candidates []candidate
+	seen := map[string]bool{}
+	for _, other := range items {
+		value := answer(other)
+		if other.id == item.id || value == "" || SameAnswer(value, correct) || seen[value] {
+			continue
+		}
+		seen[value] = true
+
+		score := -abs(utf8.RuneCountInString(value) - utf8.RuneCountInString(correct))
+		if item.wordClass != "" && other.wordClass == item.wordClass {
+			score += 100
+		}
+		candidates = append(candidates, candidate{value, score})
+	}
+
+	rng.Shuffle(len(candidates), func(a, b int) {
+		candidates[a], candidates[b] = candidates[b], candidates[a]
+	})
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].score > candidates[b].score
+	})
+
+	var picked []string
+	for _, c := range candidates {
+		if len(picked) == count {
+			break
+		}
+		picked = append(picked, c.value)
+	}
+	return picked
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
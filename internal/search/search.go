@@ -0,0 +1,186 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/japanese"
+	"captoc/internal/parser"
+	"captoc/internal/vietnamese"
+)
+
+// Manifest lists the shards of the search index
+type Manifest struct {
+	// One shard per content type
+	Shards []ShardInfo `json:"shards"`
+}
+
+// ShardInfo describes a shard in the manifest
+type ShardInfo struct {
+	// Content type the shard covers
+	ContentType string `json:"type"`
+	// Display title of the content type
+	Title string `json:"title"`
+	// Path of the shard file, relative to the index directory
+	File string `json:"file"`
+	// Number of entries in the shard
+	Count int `json:"count"`
+}
+
+// Shard holds the index entries of one content type
+type Shard struct {
+	// Content type the shard covers
+	ContentType string `json:"type"`
+	// Display title of the content type
+	Title string `json:"title"`
+	// Pages referenced by the entries
+	Pages []Page `json:"pages"`
+	// Indexed rows
+	Entries []Entry `json:"entries"`
+}
+
+// Page is a content page referenced by index entries
+type Page struct {
+	// ID of the content (from filename)
+	ID string `json:"id"`
+	// Site-relative URL of the page, without the base URL
+	URL string `json:"url"`
+}
+
+// Entry is a single searchable row. Keys are kept short to keep the index small.
+type Entry struct {
+	// Index of the page in Shard.Pages
+	Page int `json:"p"`
+	// Anchor of the row on its page
+	Anchor string `json:"a"`
+	// Text shown as the result title
+	Title string `json:"t"`
+	// Remaining displayed fields, shown under the title
+	Text string `json:"s,omitempty"`
+	// Folded text the query is matched against
+	Keys string `json:"k"`
+}
+
+// Fold normalizes text for matching: width folding, lower case, Vietnamese
+// diacritics removed and katakana folded to hiragana
+func Fold(s string) string {
+	return japanese.ToHiragana(vietnamese.Fold(japanese.Normalize(s)))
+}
+
+// Keys builds the match keys for a set of values. Values written in kana
+// also get their romaji so "taberu" finds 食べる through its reading.
+func Keys(values ...string) string {
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(s string) {
+		for _, word := range strings.Fields(s) {
+			if !seen[word] {
+				seen[word] = true
+				keys = append(keys, word)
+			}
+		}
+	}
+
+	for _, value := range values {
+		folded := Fold(value)
+		add(folded)
+		if strings.IndexFunc(folded, japanese.IsKana) >= 0 && !japanese.ContainsKanji(folded) {
+			add(strings.ReplaceAll(japanese.ToRomaji(folded), "'", ""))
+		}
+	}
+	return strings.Join(keys, " ")
+}
+
+// Build indexes every row of the given content, one shard per content type.
+// Only fields marked for display are indexed, so hidden fields such as
+// correct answers never end up in the index.
+func Build(cfg *config.Config, contents []*parser.ContentData) []*Shard {
+	shards := make(map[string]*Shard)
+
+	for _, data := range contents {
+		contentTypeConfig := cfg.ContentTypes[data.ContentType]
+
+		shard, ok := shards[data.ContentType]
+		if !ok {
+			title := contentTypeConfig.Title
+			if title == "" {
+				title = data.ContentType
+			}
+			shard = &Shard{ContentType: data.ContentType, Title: title}
+			shards[data.ContentType] = shard
+		}
+
+		shard.Pages = append(shard.Pages, Page{
+			ID:  data.ContentID,
+			URL: cfg.ContentPath(data.ContentType, data.ContentID),
+		})
+		page := len(shard.Pages) - 1
+
+		fields := contentTypeConfig.DisplayFields(data.Headers)
+		for _, row := range data.Rows {
+			var values []string
+			for _, field := range fields {
+				if value := strings.TrimSpace(row[field]); value != "" {
+					values = append(values, value)
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+
+			shard.Entries = append(shard.Entries, Entry{
+				Page:   page,
+				Anchor: row[parser.IDField],
+				Title:  values[0],
+				Text:   strings.Join(values[1:], " · "),
+				Keys:   Keys(values...),
+			})
+		}
+	}
+
+	result := make([]*Shard, 0, len(shards))
+	for _, shard := range shards {
+		result = append(result, shard)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ContentType < result[j].ContentType
+	})
+	return result
+}
+
+// Write writes the manifest and one JSON file per shard into dir
+func Write(dir string, shards []*Shard) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	manifest := Manifest{Shards: []ShardInfo{}}
+	for _, shard := range shards {
+		file := shard.ContentType + ".json"
+		if err := writeJSON(filepath.Join(dir, file), shard); err != nil {
+			return err
+		}
+		manifest.Shards = append(manifest.Shards, ShardInfo{
+			ContentType: shard.ContentType,
+			Title:       shard.Title,
+			File:        file,
+			Count:       len(shard.Entries),
+		})
+	}
+
+	return writeJSON(filepath.Join(dir, "index.json"), manifest)
+}
+
+// writeJSON writes v as compact JSON
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
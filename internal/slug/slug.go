@@ -0,0 +1,64 @@
+// Package slug turns names written in Vietnamese or Japanese into the
+// lower-case, hyphenated ASCII form used in URLs.
+package slug
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"captoc/internal/japanese"
+	"captoc/internal/vietnamese"
+)
+
+// Make returns the ASCII URL form of a name: lower case without Vietnamese
+// diacritics, full-width Latin letters and digits as their ASCII forms, kana
+// in Hepburn romaji, and runs of other characters than letters and digits
+// turned into a hyphen ("Kính ngữ" → "kinh-ngu", "Ｎ２_bài 1" → "n2-bai-1",
+// "たべもの" → "tabemono"). Kanji and other letters that cannot be spelt in
+// ASCII are left out, and a short hash of the name is added so the slug
+// stays stable and distinct ("食べ物" → "be-" followed by the hash).
+func Make(name string) string {
+	var b strings.Builder
+	hyphen, dropped := false, false
+	for _, r := range vietnamese.Fold(japanese.ToRomaji(japanese.Normalize(name))) {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			dropped = true
+			hyphen = true
+		default:
+			hyphen = true
+		}
+	}
+
+	if !dropped {
+		return b.String()
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := fmt.Sprintf("%08x", h.Sum32())[:6]
+	if b.Len() == 0 {
+		return sum
+	}
+	return b.String() + "-" + sum
+}
+
+// Path returns the URL form of a slash-separated path, each part made into
+// a slug; parts without letters or digits are kept as they are
+func Path(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if s := Make(part); s != "" {
+			parts[i] = s
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,54 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Kính ngữ", "kinh-ngu"},
+		{"N2", "n2"},
+		{"Ｎ２_bài 1", "n2-bai-1"},
+		{"Đề thi thử số 1", "de-thi-thu-so-1"},
+		{"  Bài   2 -- Động từ  ", "bai-2-dong-tu"},
+		{"n1_10", "n1-10"},
+		// Letters followed by combining marks
+		{"Ba\u0300i", "bai"},
+		// Kana in romaji
+		{"Ｎ５_たべもの", "n5-tabemono"},
+		{"コーヒー", "koohii"},
+		{"ｶﾀｶﾅ", "katakana"},
+		{"きんえん", "kin-en"},
+		// Kanji are left out, with a hash of the name
+		{"食べ物", "be-b2a1f5"},
+		{"敬語", "55447f"},
+		{"N5_食べ物", "n5-be-00d824"},
+		{"N5_飲べ物", "n5-be-e988b0"},
+		{"!!!", ""},
+	}
+
+	for _, test := range tests {
+		if got := Make(test.in); got != test.want {
+			t.Errorf("Make(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"n1/dong_tu/bai_2", "n1/dong-tu/bai-2"},
+		{"JLPT N1/Bài 1", "jlpt-n1/bai-1"},
+		{"bai1", "bai1"},
+		{"n5/たべもの", "n5/tabemono"},
+		// Parts without letters or digits are kept
+		{"n1/__/bai1", "n1/__/bai1"},
+	}
+
+	for _, test := range tests {
+		if got := Path(test.in); got != test.want {
+			t.Errorf("Path(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
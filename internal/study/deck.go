@@ -0,0 +1,157 @@
+package study
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/parser"
+	"captoc/internal/password"
+)
+
+// DefaultNewCardsPerDay is used when a content type does not set a limit
+const DefaultNewCardsPerDay = 20
+
+// Deck is the card data loaded by a study page
+type Deck struct {
+	// ID of the deck (content type and content ID)
+	ID string `json:"id"`
+	// Display title of the deck
+	Title string `json:"title"`
+	// Maximum number of new cards introduced per day
+	NewCardsPerDay int `json:"newCardsPerDay"`
+	// Cards in the deck
+	Cards []Card `json:"cards"`
+}
+
+// Card is a single flash card
+type Card struct {
+	// Stable ID used as the key of the review state in the browser
+	ID string `json:"id"`
+	// Fields shown on the front
+	Front []Field `json:"front"`
+	// Fields revealed on the back
+	Back []Field `json:"back"`
+}
+
+// Field is a labeled value on one side of a card
+type Field struct {
+	// Display label of the field
+	Label string `json:"label,omitempty"`
+	// Value of the field
+	Value string `json:"value"`
+}
+
+// DeckPath returns the site-relative URL of the deck data of a content file,
+// next to its page as the permalink of the content type places it. The deck
+// covering a whole content type uses the content ID "index".
+func DeckPath(cfg *config.Config, contentType, contentID string) string {
+	return strings.TrimSuffix(cfg.PagePath(contentType, contentID, "deck"), ".html") + ".json"
+}
+
+// CardID returns the stable ID of the card made from a row. It is the same
+// in the per-file deck and the per-content-type deck so review state is shared.
+func CardID(data *parser.ContentData, row map[string]string) string {
+	return data.ContentType + "/" + data.ContentID + "/" + row[parser.IDField]
+}
+
+// BuildDeck builds a deck from the rows of one or more content files of the
+// same content type
+func BuildDeck(cfg *config.Config, contentType, contentID string, contents ...*parser.ContentData) *Deck {
+	contentTypeConfig := cfg.ContentTypes[contentType]
+	front, back := cardFields(contentTypeConfig)
+
+	deck := &Deck{
+		ID:             contentType + "/" + contentID,
+		Title:          contentTypeConfig.Title,
+		NewCardsPerDay: contentTypeConfig.Study.NewCardsPerDay,
+		Cards:          []Card{},
+	}
+	if deck.NewCardsPerDay <= 0 {
+		deck.NewCardsPerDay = DefaultNewCardsPerDay
+	}
+
+	labels := make(map[string]string)
+	for _, field := range contentTypeConfig.Fields {
+		labels[field.Name] = field.Label
+	}
+
+	for _, data := range contents {
+		for _, row := range data.Rows {
+			card := Card{
+				ID:    CardID(data, row),
+				Front: sideFields(row, front, labels),
+				Back:  sideFields(row, back, labels),
+			}
+			// A card without anything on the front cannot be studied
+			if len(card.Front) == 0 {
+				continue
+			}
+			deck.Cards = append(deck.Cards, card)
+		}
+	}
+
+	return deck
+}
+
+// cardFields returns the front and back fields of a content type, defaulting
+// to the first displayed field on the front and the others on the back
+func cardFields(contentTypeConfig config.ContentTypeConfig) ([]string, []string) {
+	front, back := contentTypeConfig.Study.Front, contentTypeConfig.Study.Back
+	if len(front) > 0 && len(back) > 0 {
+		return front, back
+	}
+
+	var displayed []string
+	for _, field := range contentTypeConfig.Fields {
+		if field.Display {
+			displayed = append(displayed, field.Name)
+		}
+	}
+	if len(displayed) == 0 {
+		return front, back
+	}
+	if len(front) == 0 {
+		front = displayed[:1]
+	}
+	if len(back) == 0 {
+		back = displayed[1:]
+	}
+	return front, back
+}
+
+// sideFields collects the non-empty fields of a row for one side of a card
+func sideFields(row map[string]string, names []string, labels map[string]string) []Field {
+	fields := []Field{}
+	for _, name := range names {
+		if value := strings.TrimSpace(row[name]); value != "" {
+			fields = append(fields, Field{Label: labels[name], Value: value})
+		}
+	}
+	return fields
+}
+
+// WriteDeck writes a deck as JSON to path, encrypted with pass unless it
+// is empty
+func WriteDeck(path string, deck *Deck, pass string) error {
+	data, err := json.Marshal(deck)
+	if err != nil {
+		return fmt.Errorf("failed to encode deck %s: %w", deck.ID, err)
+	}
+	if pass != "" {
+		envelope, err := password.Encrypt(pass, data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt deck %s: %w", deck.ID, err)
+		}
+		if data, err = json.Marshal(envelope); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,116 @@
+// Package taxonomy gathers tagged rows across all content for the tag
+// pages, such as every row tagged n2 whatever file or type it is in.
+package taxonomy
+
+import (
+	"sort"
+	"strings"
+
+	"captoc/internal/config"
+	"captoc/internal/parser"
+)
+
+// maxDetail is the number of fields after the title shown for an item
+const maxDetail = 2
+
+// Tag is a tag and the rows carrying it
+type Tag struct {
+	// Tag as first written
+	Name string
+	// URL form of the tag
+	Slug string
+	// Tagged rows, grouped by content type and file in build order
+	Items []Item
+}
+
+// Item is a tagged row listed on a tag page
+type Item struct {
+	// Content type title
+	Type string
+	// Content file ID
+	ContentID string
+	// URL of the row on its page
+	URL string
+	// First displayed field of the row
+	Title string
+	// Following displayed fields
+	Detail string
+}
+
+// Build collects the tags of every row, sorted by name. Derived content and
+// collections, which repeat other files, and password-protected files are
+// left out.
+func Build(cfg *config.Config, contents []*parser.ContentData) []*Tag {
+	bySlug := make(map[string]*Tag)
+	for _, data := range contents {
+		contentTypeConfig := cfg.ContentTypes[data.ContentType]
+		if contentTypeConfig.Derive.From != "" || cfg.IsCollection(data.ContentType, data.ContentID) ||
+			cfg.PasswordEnv(data.ContentType, data.ContentID) != "" {
+			continue
+		}
+
+		typeTitle := contentTypeConfig.Title
+		if typeTitle == "" {
+			typeTitle = data.ContentType
+		}
+		fields := summaryFields(contentTypeConfig, data)
+
+		for _, row := range data.Rows {
+			tags := data.RowTags[row[parser.IDField]]
+			if len(tags) == 0 {
+				continue
+			}
+
+			item := Item{
+				Type:      typeTitle,
+				ContentID: data.ContentID,
+				URL:       cfg.BaseURL + cfg.ContentPath(data.ContentType, data.ContentID) + "#" + row[parser.IDField],
+			}
+			var values []string
+			for _, field := range fields {
+				if value := strings.TrimSpace(row[field]); value != "" {
+					values = append(values, value)
+				}
+			}
+			if len(values) > 0 {
+				item.Title = values[0]
+				item.Detail = strings.Join(values[1:min(len(values), maxDetail+1)], " · ")
+			}
+
+			for _, name := range tags {
+				slug := parser.TagSlug(name)
+				tag, ok := bySlug[slug]
+				if !ok {
+					tag = &Tag{Name: name, Slug: slug}
+					bySlug[slug] = tag
+				}
+				tag.Items = append(tag.Items, item)
+			}
+		}
+	}
+
+	tags := make([]*Tag, 0, len(bySlug))
+	for _, tag := range bySlug {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Slug < tags[j].Slug
+	})
+	return tags
+}
+
+// summaryFields returns the fields describing a row on a tag page: the
+// displayed fields without the tag columns, question numbers and options
+func summaryFields(contentTypeConfig config.ContentTypeConfig, data *parser.ContentData) []string {
+	skip := map[string]bool{parser.IDField: true}
+	for _, role := range []string{config.RoleTags, config.RoleLevel, config.RoleNumber, config.RoleOptions} {
+		skip[contentTypeConfig.FieldName(role)] = true
+	}
+	var fields []string
+	for _, field := range contentTypeConfig.DisplayFields(data.Headers) {
+		if !skip[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
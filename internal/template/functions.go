@@ -1,16 +1,21 @@
 package template
 
 import (
-	"encoding/json"
+	"fmt"
 	"html/template"
 	"strings"
 	"time"
+
+	"captoc/internal/japanese"
+	"captoc/internal/media"
+	"captoc/internal/parser"
+	"captoc/internal/quiz"
 )
 
 // TemplateFunctions returns a map of custom functions for templates
 func TemplateFunctions() template.FuncMap {
 	return template.FuncMap{
-		"parseOptions": parseOptions,
+		"parseOptions": quiz.ParseOptions,
 		"formatYear":   formatYear,
 		"capitalize":   capitalize,
 		"hasPrefix":    strings.HasPrefix,
@@ -22,9 +27,58 @@ func TemplateFunctions() template.FuncMap {
 		"slice":        slice,
 		"sub":          sub,
 		"add":          add,
+		"mul":          mul,
+		"dict":         dict,
+		"seq":          seq,
+		"letter":       quiz.Letter,
 		"gt":           gt,
 		"lt":           lt,
+		"sameAnswer":   quiz.SameAnswer,
+		"questionType": quiz.QuestionType,
+		"answers":      quiz.Answers,
+		"isAnswer":     quiz.IsAnswer,
+		"answerText":   quiz.AnswerText,
+		"keyLetters":   quiz.KeyLetters,
+		"matchPairs":   quiz.ParsePairs,
+		"matchChoices": quiz.MatchChoices,
+		"jsonList":     quiz.FormatOptions,
+		"explainMode":  quiz.ExplanationMode,
+		"explanation":  quiz.Explanation,
+		"references":   quiz.ParseReferences,
+		"answerData":   quiz.NewAnswerData,
+		"sealAnswers":  quiz.SealAnswers,
+		"tagSlug":      parser.TagSlug,
+		"tagSlugs":     tagSlugs,
+		"imageRefs":    media.ParseImages,
+		"stripImages":  media.StripImages,
+		"toHiragana":   japanese.ToHiragana,
+		"toKatakana":   japanese.ToKatakana,
+		"toRomaji":     japanese.ToRomaji,
+		"fromRomaji":   japanese.FromRomaji,
+		"normalize":    japanese.Normalize,
+		"toFullWidth":  japanese.ToFullWidth,
+		"toHalfWidth":  japanese.ToHalfWidth,
+		"isKanji":      japanese.AllKanji,
+		"isKana":       japanese.AllKana,
+		"hasKanji":     japanese.ContainsKanji,
+		"kanjiOf":      japanese.KanjiOf,
+		"scriptOf":     japanese.Classify,
+
+		// Replaced per page once the templates are parsed
+		"include": func(string, interface{}) (template.HTML, error) {
+			return "", fmt.Errorf("include is not available outside page templates")
+		},
+	}
+}
+
+// tagSlugs returns the slugs of tags separated by spaces, for the
+// data-tags attribute the tag filter reads
+func tagSlugs(tags []string) string {
+	slugs := make([]string, len(tags))
+	for i, tag := range tags {
+		slugs[i] = parser.TagSlug(tag)
 	}
+	return strings.Join(slugs, " ")
 }
 
 // slice returns a substring of s from start to end
@@ -51,6 +105,37 @@ func add(x, y int) int {
 	return x + y
 }
 
+// mul returns x * y
+func mul(x, y int) int {
+	return x * y
+}
+
+// dict builds a map from alternating keys and values, for passing several
+// values to a partial template
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects an even number of arguments")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// seq returns the integers from 1 to n
+func seq(n int) []int {
+	numbers := make([]int, 0, n)
+	for i := 1; i <= n; i++ {
+		numbers = append(numbers, i)
+	}
+	return numbers
+}
+
 // gt returns x > y
 func gt(x, y int) bool {
 	return x > y
@@ -61,31 +146,6 @@ func lt(x, y int) bool {
 	return x < y
 }
 
-// parseOptions parses a string representation of an array into a slice of strings
-func parseOptions(s string) []string {
-	// Try to parse as JSON first
-	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
-		// Replace single quotes with double quotes for JSON parsing
-		s = strings.ReplaceAll(s, "'", "\"")
-		var options []string
-		if err := json.Unmarshal([]byte(s), &options); err == nil {
-			return options
-		}
-	}
-	
-	// If JSON parsing fails, try simple comma separation
-	parts := strings.Split(s, ",")
-	options := make([]string, 0, len(parts))
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p != "" {
-			options = append(options, p)
-		}
-	}
-	
-	return options
-}
-
 // formatYear extracts the year from a date string
 func formatYear(date string) string {
 	// Try to parse common date formats
@@ -0,0 +1,136 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"captoc/internal/config"
+)
+
+// buildMenu returns the menu of the configured mode from the content files
+func buildMenu(cfg *config.Config, contentMap map[string][]NavItem) ([]config.MenuItem, error) {
+	var menu []config.MenuItem
+	switch cfg.MenuMode {
+	case "", config.MenuManual:
+	case config.MenuAuto:
+		menu = autoMenu(cfg, contentMap)
+	default:
+		return nil, fmt.Errorf("unknown menu mode %q", cfg.MenuMode)
+	}
+
+	items, err := resolveMenuItems(cfg, contentMap, cfg.Menu)
+	if err != nil {
+		return nil, err
+	}
+	return append(menu, items...), nil
+}
+
+// autoMenu returns an item per configured content type with files, linking
+// to its listing page, with its top-level sections as submenu
+func autoMenu(cfg *config.Config, contentMap map[string][]NavItem) []config.MenuItem {
+	contentTypes := make([]string, 0, len(cfg.ContentTypes))
+	for contentType := range cfg.ContentTypes {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+
+	var menu []config.MenuItem
+	for _, contentType := range contentTypes {
+		if len(contentMap[contentType]) == 0 {
+			continue
+		}
+		item := config.MenuItem{
+			Label:       typeTitle(cfg, contentType),
+			URL:         cfg.BaseURL + cfg.SectionPath(contentType, ""),
+			ContentType: contentType,
+		}
+		for _, section := range contentMap[contentType] {
+			if section.Children != nil {
+				item.Children = append(item.Children, config.MenuItem{
+					Label:       section.Label(),
+					URL:         section.URL,
+					ContentType: contentType,
+				})
+			}
+		}
+		menu = append(menu, item)
+	}
+	return menu
+}
+
+// resolveMenuItems returns copies of menu items with the base URL applied
+// to their URLs and their references resolved, labelled with the title of
+// the page they refer to unless they have a label
+func resolveMenuItems(cfg *config.Config, contentMap map[string][]NavItem, items []config.MenuItem) ([]config.MenuItem, error) {
+	resolved := make([]config.MenuItem, 0, len(items))
+	for _, item := range items {
+		switch {
+		case item.Ref != "" && item.URL != "":
+			return nil, fmt.Errorf("menu item %q has both a url and a ref", item.Label)
+		case item.Ref != "":
+			contentType, target, err := resolveRef(cfg, contentMap, item.Ref)
+			if err != nil {
+				return nil, fmt.Errorf("menu item %q: %w", item.Ref, err)
+			}
+			item.URL = target.URL
+			if item.Label == "" {
+				item.Label = target.Label()
+			}
+			if item.ContentType == "" {
+				item.ContentType = contentType
+			}
+		case item.URL != "":
+			item.URL = cfg.RefURL(item.URL)
+		}
+		if item.Label == "" {
+			return nil, fmt.Errorf("menu item %q has no label", item.URL)
+		}
+
+		children, err := resolveMenuItems(cfg, contentMap, item.Children)
+		if err != nil {
+			return nil, err
+		}
+		item.Children = children
+		resolved = append(resolved, item)
+	}
+	return resolved, nil
+}
+
+// resolveRef finds the page a menu reference points to: the listing page
+// of a content type, or a file or section of one
+func resolveRef(cfg *config.Config, contentMap map[string][]NavItem, ref string) (string, NavItem, error) {
+	target, anchor, hasAnchor := strings.Cut(strings.TrimSpace(ref), "#")
+	contentType, contentID, _ := strings.Cut(target, "/")
+	items := contentMap[contentType]
+	if len(items) == 0 {
+		return "", NavItem{}, fmt.Errorf("content type %s has no pages", contentType)
+	}
+
+	item := NavItem{Title: typeTitle(cfg, contentType), URL: cfg.BaseURL + cfg.SectionPath(contentType, "")}
+	if contentID != "" {
+		found := findNavItem(items, strings.TrimSuffix(contentID, ".html"))
+		if found == nil {
+			return "", NavItem{}, fmt.Errorf("page %s not found", target)
+		}
+		item = *found
+	}
+	if hasAnchor {
+		item.URL += "#" + anchor
+	}
+	return contentType, item, nil
+}
+
+// findNavItem returns the file or section with the given ID, looking into
+// the sections holding it
+func findNavItem(items []NavItem, id string) *NavItem {
+	for i := range items {
+		if items[i].ID == id {
+			return &items[i]
+		}
+		if items[i].Contains(id) {
+			return findNavItem(items[i].Children, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,409 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"captoc/internal/config"
+	"captoc/internal/natsort"
+	"captoc/internal/parser"
+	"captoc/internal/vietnamese"
+)
+
+// NavItem is a content file or section listed in the sidebar navigation
+type NavItem struct {
+	// Content ID, the file name without extension prefixed with its
+	// sections, or the path of a section
+	ID string
+	// Title from the metadata of the file or section
+	Title string
+	// Description from the metadata of the file or section
+	Description string
+	// Position from the metadata, 0 when unset
+	Order int
+	// URL of the page of the file, or of the index page of the section
+	URL string
+	// Files and subsections of a section, nil for a file
+	Children []NavItem
+
+	// Modification time of the data file or directory
+	modified time.Time
+}
+
+// Nav is the navigation shared by the pages of a build
+type Nav struct {
+	// Files and sections of every content type, for the sidebar
+	ContentMap map[string][]NavItem
+	// Menu with references resolved and the base URL applied
+	Menu []config.MenuItem
+}
+
+// BuildNav returns the navigation of the pages built from contents: the
+// data files with the files derived from them and the collections. Derived
+// files are listed when contents has them, so those skipped for having no
+// questions are left out. A menu reference to a page that does not exist
+// is an error, so a renamed file cannot leave a dead link in the menu.
+func BuildNav(cfg *config.Config, contents []*parser.ContentData) (*Nav, error) {
+	contentMap, err := scanContentFiles(cfg, contents)
+	if err != nil {
+		return nil, err
+	}
+	menu, err := buildMenu(cfg, contentMap)
+	if err != nil {
+		return nil, fmt.Errorf("menu: %w", err)
+	}
+	return &Nav{ContentMap: contentMap, Menu: menu}, nil
+}
+
+// scanContentFiles scans the data directory for content files, leaving out
+// drafts and ordering them as their content type and metadata ask. Section
+// directories become items holding their files. Only the files of contents
+// are listed, so files skipped for errors and derived files skipped for
+// having no questions leave no dead links.
+func scanContentFiles(cfg *config.Config, contents []*parser.ContentData) (map[string][]NavItem, error) {
+	contentMap := make(map[string][]NavItem)
+
+	// Files that were parsed, leaving out those skipped for errors
+	built := make(map[string]map[string]*parser.ContentData)
+	for _, data := range contents {
+		if built[data.ContentType] == nil {
+			built[data.ContentType] = make(map[string]*parser.ContentData)
+		}
+		built[data.ContentType][data.ContentID] = data
+	}
+
+	// Read data directory
+	dataDirs, err := os.ReadDir(cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Process each content type directory
+	for _, dir := range dataDirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		contentType := dir.Name()
+		items, err := scanSection(cfg, built[contentType], contentType, filepath.Join(cfg.DataDir, contentType), "")
+		if err != nil {
+			return nil, err
+		}
+		contentMap[contentType] = items
+	}
+
+	// Derived content types have one file per source file and direction,
+	// in the sections of the source
+	for contentType, contentTypeConfig := range cfg.ContentTypes {
+		if contentTypeConfig.Derive.From != "" {
+			contentMap[contentType] = deriveNavItems(cfg, contentType, contentTypeConfig.Derive, contentMap[contentTypeConfig.Derive.From], built[contentType])
+		}
+	}
+
+	// Collections are listed after the files of their content type
+	ids := make([]string, 0, len(cfg.Collections))
+	for id := range cfg.Collections {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		contentType := cfg.Collections[id].Type
+		item := newNavItem(id, parser.Meta{Title: cfg.Collections[id].Title})
+		item.URL = cfg.BaseURL + cfg.ContentPath(contentType, id)
+		contentMap[contentType] = append(contentMap[contentType], item)
+	}
+
+	return contentMap, nil
+}
+
+// scanSection lists the files of built and the subsections of a content
+// type directory or section; prefix is the section path followed by a
+// slash. Sections without files are left out.
+func scanSection(cfg *config.Config, built map[string]*parser.ContentData, contentType, dir, prefix string) ([]NavItem, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []NavItem{}
+	for _, entry := range entries {
+		entryPath := filepath.Join(dir, entry.Name())
+		ext := filepath.Ext(entry.Name())
+		id := prefix + strings.TrimSuffix(entry.Name(), ext)
+		if entry.IsDir() {
+			id = prefix + entry.Name()
+		} else if (ext != ".csv" && ext != ".json") || parser.IsPassageFile(entry.Name()) || id == prefix+parser.IndexID {
+			// Only include supported file types; index is the name of the
+			// index pages
+			continue
+		}
+
+		var meta parser.Meta
+		if entry.IsDir() {
+			if meta, err = parser.ReadMeta(entryPath); err != nil {
+				return nil, fmt.Errorf("failed to read metadata of %s: %w", entryPath, err)
+			}
+		} else if data := built[id]; data != nil {
+			meta = data.Meta
+		} else {
+			continue
+		}
+		if meta.Draft {
+			continue
+		}
+
+		item := newNavItem(id, meta)
+		if info, err := entry.Info(); err == nil {
+			item.modified = info.ModTime()
+		}
+		if entry.IsDir() {
+			if item.Children, err = scanSection(cfg, built, contentType, entryPath, id+"/"); err != nil {
+				return nil, err
+			}
+			if len(item.Children) == 0 {
+				continue
+			}
+			item.URL = cfg.BaseURL + cfg.SectionPath(contentType, id)
+		} else {
+			item.URL = cfg.BaseURL + cfg.ContentPath(contentType, id)
+		}
+		items = append(items, item)
+	}
+
+	if err := sortNavItems(items, cfg.ContentTypes[contentType]); err != nil {
+		return nil, fmt.Errorf("content type %s: %w", contentType, err)
+	}
+	return items, nil
+}
+
+// deriveNavItems returns the items of a derived content type from those of
+// its source, with the source's sections, leaving out the files that were
+// not built
+func deriveNavItems(cfg *config.Config, contentType string, derive config.DeriveConfig, sources []NavItem, built map[string]*parser.ContentData) []NavItem {
+	items := []NavItem{}
+	for _, source := range sources {
+		if source.Children != nil {
+			section := source
+			section.URL = cfg.BaseURL + cfg.SectionPath(contentType, source.ID)
+			section.Children = deriveNavItems(cfg, contentType, derive, source.Children, built)
+			if len(section.Children) > 0 {
+				items = append(items, section)
+			}
+			continue
+		}
+		for _, mode := range derive.ModeList() {
+			id := derive.ContentID(source.ID, mode)
+			if built[id] == nil {
+				continue
+			}
+			items = append(items, NavItem{
+				ID:    id,
+				Title: derive.Title(source.Title, mode),
+				Order: source.Order,
+				URL:   cfg.BaseURL + cfg.ContentPath(contentType, id),
+			})
+		}
+	}
+	return items
+}
+
+// Sections returns the sections of every content type with files, parents
+// before their subsections, for writing their index pages. The first one of
+// each type, with an empty ID, is the whole content type.
+func (n *Nav) Sections(cfg *config.Config) map[string][]NavItem {
+	sections := make(map[string][]NavItem)
+	var collect func(contentType string, items []NavItem)
+	collect = func(contentType string, items []NavItem) {
+		for _, item := range items {
+			if item.Children != nil {
+				sections[contentType] = append(sections[contentType], item)
+				collect(contentType, item.Children)
+			}
+		}
+	}
+	for contentType, items := range n.ContentMap {
+		if len(items) == 0 {
+			continue
+		}
+		root := NavItem{
+			Title:       typeTitle(cfg, contentType),
+			Description: cfg.ContentTypes[contentType].Description,
+			URL:         cfg.BaseURL + cfg.SectionPath(contentType, ""),
+			Children:    items,
+		}
+		sections[contentType] = append(sections[contentType], root)
+		collect(contentType, items)
+	}
+	return sections
+}
+
+// Breadcrumb is a step of the path from the home page to the current page
+type Breadcrumb struct {
+	Label string
+	// URL of the step, empty for the current page
+	URL string
+}
+
+// breadcrumbs returns the path to the page of a content file, section or
+// generated page, ending with the page itself
+func breadcrumbs(cfg *config.Config, contentMap map[string][]NavItem, data *parser.ContentData) []Breadcrumb {
+	crumbs := []Breadcrumb{{Label: "Trang chủ", URL: cfg.BaseURL + "/index.html"}}
+
+	switch data.ContentType {
+	case "index":
+		return nil
+	case "search":
+		return append(crumbs, Breadcrumb{Label: "Tìm kiếm", URL: cfg.BaseURL + "/search.html"})
+	case config.KanjiDir:
+		crumbs = append(crumbs, Breadcrumb{Label: cfg.Kanji.Title, URL: cfg.BaseURL + cfg.KanjiPath("")})
+		if data.ContentID != parser.IndexID {
+			crumbs = append(crumbs, Breadcrumb{Label: data.Label(), URL: cfg.BaseURL + cfg.KanjiPath(data.ContentID)})
+		}
+		return crumbs
+	case config.TagsDir:
+		crumbs = append(crumbs, Breadcrumb{Label: "Thẻ", URL: cfg.BaseURL + cfg.TagPath("")})
+		if data.ContentID != parser.IndexID {
+			crumbs = append(crumbs, Breadcrumb{Label: data.Label(), URL: cfg.BaseURL + cfg.TagPath(data.ContentID)})
+		}
+		return crumbs
+	}
+
+	crumbs = append(crumbs, Breadcrumb{Label: typeTitle(cfg, data.ContentType), URL: cfg.BaseURL + cfg.SectionPath(data.ContentType, "")})
+
+	// Sections of the file, titled as in the navigation
+	parts := strings.Split(data.ContentID, "/")
+	items := contentMap[data.ContentType]
+	for i := range parts[:len(parts)-1] {
+		id := strings.Join(parts[:i+1], "/")
+		crumb := Breadcrumb{Label: parts[i], URL: cfg.BaseURL + cfg.SectionPath(data.ContentType, id)}
+		var children []NavItem
+		for _, item := range items {
+			if item.ID == id {
+				crumb.Label = item.Label()
+				children = item.Children
+				break
+			}
+		}
+		crumbs = append(crumbs, crumb)
+		items = children
+	}
+
+	if parts[len(parts)-1] != parser.IndexID {
+		crumbs = append(crumbs, Breadcrumb{Label: data.Label(), URL: cfg.BaseURL + cfg.ContentPath(data.ContentType, data.ContentID)})
+	}
+	return crumbs
+}
+
+// neighbours returns the files before and after a content file in the
+// navigation of its content type, across sections
+func neighbours(items []NavItem, contentID string) (prev, next *NavItem) {
+	var files []NavItem
+	var flatten func(items []NavItem)
+	flatten = func(items []NavItem) {
+		for _, item := range items {
+			if item.Children != nil {
+				flatten(item.Children)
+			} else {
+				files = append(files, item)
+			}
+		}
+	}
+	flatten(items)
+
+	for i := range files {
+		if files[i].ID != contentID {
+			continue
+		}
+		if i > 0 {
+			prev = &files[i-1]
+		}
+		if i < len(files)-1 {
+			next = &files[i+1]
+		}
+		break
+	}
+	return prev, next
+}
+
+// typeTitle returns the title of a content type, or its name when it has
+// none
+func typeTitle(cfg *config.Config, contentType string) string {
+	if title := cfg.ContentTypes[contentType].Title; title != "" {
+		return title
+	}
+	return contentType
+}
+
+// newNavItem returns the navigation entry of a content file
+func newNavItem(id string, meta parser.Meta) NavItem {
+	return NavItem{ID: id, Title: meta.Title, Description: meta.Description, Order: meta.Order}
+}
+
+// Label returns the title of the item, or its ID when it has none. Items
+// in sections default to their own name rather than the whole path.
+func (n NavItem) Label() string {
+	if n.Title != "" {
+		return n.Title
+	}
+	return path.Base(n.ID)
+}
+
+// Contains reports whether a content file lies in the section of the item
+func (n NavItem) Contains(contentID string) bool {
+	return n.Children != nil && strings.HasPrefix(contentID, n.ID+"/")
+}
+
+// sortNavItems orders the files of a content type: those named in its
+// order list first, then the rest by its sort key, ties broken by name
+func sortNavItems(items []NavItem, contentTypeConfig config.ContentTypeConfig) error {
+	var less func(a, b NavItem) bool
+	switch contentTypeConfig.Sort {
+	case "", config.SortOrder:
+		// Files with an order come first, lowest first
+		less = func(a, b NavItem) bool {
+			if (a.Order != 0) != (b.Order != 0) {
+				return a.Order != 0
+			}
+			return a.Order < b.Order
+		}
+	case config.SortName:
+		less = func(a, b NavItem) bool { return false }
+	case config.SortTitle:
+		// Compared without accents, so "Bài 2" sorts among "bai"
+		less = func(a, b NavItem) bool {
+			return natsort.Less(vietnamese.Fold(a.Label()), vietnamese.Fold(b.Label()))
+		}
+	case config.SortModified:
+		less = func(a, b NavItem) bool { return a.modified.After(b.modified) }
+	default:
+		return fmt.Errorf("unknown sort %q", contentTypeConfig.Sort)
+	}
+
+	listed := make(map[string]int, len(contentTypeConfig.Order))
+	for i, id := range contentTypeConfig.Order {
+		listed[id] = i + 1
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if listed[a.ID] != 0 || listed[b.ID] != 0 {
+			if listed[a.ID] == 0 || listed[b.ID] == 0 {
+				return listed[a.ID] != 0
+			}
+			return listed[a.ID] < listed[b.ID]
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return natsort.Less(a.ID, b.ID)
+	})
+	return nil
+}
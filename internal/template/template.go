@@ -4,12 +4,19 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"time"
 
 	"captoc/internal/config"
+	"captoc/internal/kanji"
+	"captoc/internal/media"
 	"captoc/internal/parser"
+	"captoc/internal/password"
+	"captoc/internal/quiz"
+	"captoc/internal/taxonomy"
 )
 
 // TemplateData holds data for template rendering
@@ -20,107 +27,146 @@ type TemplateData struct {
 	Content *parser.ContentData
 	// Page title
 	Title string
+	// Metadata of the current content file
+	Meta parser.Meta
 	// Current timestamp
 	Timestamp string
-	// Navigation menu
+	// Navigation menu, with references resolved and the base URL applied
 	Menu []config.MenuItem
 	// ContentMap for sidebar navigation
-	ContentMap map[string][]string
+	ContentMap map[string][]NavItem
 	// Content type config for the current content
 	ContentTypeConfig *config.ContentTypeConfig
+	// URL of the deck data, for study pages
+	DeckURL string
+	// Shuffled test form being rendered, for variant and answer key pages
+	Variant *quiz.Variant
+	// Key the answers on the page are encrypted with, empty when the page
+	// shows answers in plain markup
+	AnswerKey string
+	// Encrypted page, for the unlock page of a password-protected page
+	Locked *password.Envelope
+	// ID the unlock page remembers the entered password under
+	LockID string
+	// Kanji of a kanji page
+	Kanji *kanji.Entry
+	// Kanji listed on the kanji index page
+	KanjiList []*kanji.Entry
+	// Tag of a tag page
+	Tag *taxonomy.Tag
+	// Tags listed on the tag index page
+	TagList []*taxonomy.Tag
+	// Section of a section or content type index page
+	Section *NavItem
+	// Number of rows of the files listed on an index page, by content ID
+	RowCounts map[string]int
+	// Path from the home page to the current page
+	Breadcrumbs []Breadcrumb
+	// Files before and after the current one in the navigation
+	Prev *NavItem
+	Next *NavItem
+
+	// Password the page is encrypted with, empty for public pages
+	password string
 }
 
-// RenderTemplate renders a template with the given content data
-func RenderTemplate(contentType string, data *parser.ContentData, outputPath string, cfg *config.Config) error {
-	// Get the content type configuration
-	contentTypeConfig, found := cfg.ContentTypes[contentType]
-	if !found {
-		return fmt.Errorf("content type configuration not found: %s", contentType)
-	}
-	
-	// Determine template name from content type config or fallback to content type
-	templateName := contentTypeConfig.Template
-	if templateName == "" {
-		templateName = contentType
-	}
+// PageURL returns the URL of a page generated for the current content, such
+// as its study page, with the base URL applied
+func (d *TemplateData) PageURL(kind string) string {
+	return d.Config.BaseURL + d.Config.PagePath(d.Content.ContentType, d.Content.ContentID, kind)
+}
 
-	// Select template file
-	templateFile := filepath.Join(cfg.TemplateDir, templateName+".gohtml")
-	layoutFile := filepath.Join(cfg.TemplateDir, "layout.gohtml")
-	
-	// Check if the template file exists
-	if _, err := os.Stat(templateFile); os.IsNotExist(err) {
-		fmt.Printf("Template file %s not found, falling back to default\n", templateFile)
-		templateFile = filepath.Join(cfg.TemplateDir, "default.gohtml")
-		// If default template doesn't exist either, return an error
-		if _, err := os.Stat(templateFile); os.IsNotExist(err) {
-			return fmt.Errorf("no template found for content type: %s and default template missing", contentType)
-		}
-	}
-	
-	// Check if layout file exists
-	if _, err := os.Stat(layoutFile); os.IsNotExist(err) {
-		return fmt.Errorf("layout template not found: %s", layoutFile)
+// addBreadcrumb adds a page generated for the current content, such as its
+// study page, after the content in the breadcrumbs
+func (d *TemplateData) addBreadcrumb(label string) {
+	d.Breadcrumbs = append(d.Breadcrumbs, Breadcrumb{Label: label})
+}
+
+// protectAnswers sets the answer key of the page of the given kind when
+// its content type protects answers there
+func (d *TemplateData) protectAnswers(kind string, exam bool) {
+	if quiz.Protects(*d.ContentTypeConfig, exam) {
+		d.AnswerKey = quiz.PageKey(d.Config.PagePath(d.Content.ContentType, d.Content.ContentID, kind))
 	}
-	
-	fmt.Printf("Using template files: %s and %s\n", layoutFile, templateFile)
+}
 
-	// Find and organize content files for navigation
-	contentMap, err := scanContentFiles(cfg)
+// RenderTemplate renders a template with the given content data
+func RenderTemplate(contentType string, data *parser.ContentData, outputPath string, cfg *config.Config, nav *Nav) error {
+	templateFile, err := contentTemplateFile(cfg, contentType, data)
 	if err != nil {
 		return err
 	}
 
 	// Create template data
-	templateData := &TemplateData{
-		Config:           cfg,
-		Content:          data,
-		Title:            fmt.Sprintf("%s - %s", cfg.Name, data.ContentID),
-		Timestamp:        time.Now().Format("2006-01-02 15:04:05"),
-		Menu:             cfg.Menu,
-		ContentMap:       contentMap,
-		ContentTypeConfig: &contentTypeConfig,
+	templateData, err := newContentTemplateData(cfg, nav, contentType, data, fmt.Sprintf("%s - %s", cfg.Name, data.Label()))
+	if err != nil {
+		return err
 	}
+	templateData.protectAnswers("", false)
+	templateData.Prev, templateData.Next = neighbours(templateData.ContentMap[contentType], data.ContentID)
 
-	// Parse the templates
-	tmpl, err := template.New("layout").Funcs(TemplateFunctions()).ParseFiles(layoutFile, templateFile)
+	return renderPage(cfg, templateFile, templateData, outputPath)
+}
+
+// RenderVariant renders a shuffled test form with the content type's template
+func RenderVariant(contentType string, variant *quiz.Variant, outputPath string, cfg *config.Config, nav *Nav) error {
+	templateFile, err := contentTemplateFile(cfg, contentType, variant.Content)
 	if err != nil {
 		return err
 	}
 
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+	title := fmt.Sprintf("%s - %s - Đề %d", cfg.Name, variant.Content.Label(), variant.Number)
+	templateData, err := newContentTemplateData(cfg, nav, contentType, variant.Content, title)
+	if err != nil {
 		return err
 	}
+	templateData.Variant = variant
+	templateData.protectAnswers(variant.Kind(), false)
+	templateData.addBreadcrumb(fmt.Sprintf("Đề %d", variant.Number))
+
+	return renderPage(cfg, templateFile, templateData, outputPath)
+}
 
-	// Open output file
-	outFile, err := os.Create(outputPath)
+// RenderAnswerKey renders the answer key of a shuffled test form
+func RenderAnswerKey(contentType string, variant *quiz.Variant, outputPath string, cfg *config.Config, nav *Nav) error {
+	title := fmt.Sprintf("%s - %s - Đề %d - Đáp án", cfg.Name, variant.Content.Label(), variant.Number)
+	templateData, err := newContentTemplateData(cfg, nav, contentType, variant.Content, title)
 	if err != nil {
 		return err
 	}
-	defer outFile.Close()
+	templateData.Variant = variant
+	templateData.addBreadcrumb(fmt.Sprintf("Đề %d - Đáp án", variant.Number))
 
-	// Execute the template
-	return tmpl.ExecuteTemplate(outFile, "layout", templateData)
+	return renderPage(cfg, filepath.Join(cfg.TemplateDir, "answerkey.gohtml"), templateData, outputPath)
 }
 
-// RenderIndex generates the index page
-func RenderIndex(cfg *config.Config) error {
-	// Load index template
-	indexFile := filepath.Join(cfg.TemplateDir, "index.gohtml")
-	layoutFile := filepath.Join(cfg.TemplateDir, "layout.gohtml")
-
-	// Check if template exists
-	if _, err := os.Stat(indexFile); os.IsNotExist(err) {
-		return fmt.Errorf("index template not found: %s", indexFile)
+// RenderStudy renders the spaced-repetition study page of a deck. The deck
+// data itself is loaded by the page from deckURL.
+func RenderStudy(contentType string, data *parser.ContentData, deckURL, outputPath string, cfg *config.Config, nav *Nav) error {
+	templateData, err := newContentTemplateData(cfg, nav, contentType, data, fmt.Sprintf("%s - %s - Ôn tập", cfg.Name, data.Label()))
+	if err != nil {
+		return err
 	}
+	templateData.DeckURL = cfg.BaseURL + deckURL
+	templateData.addBreadcrumb("Ôn tập")
+
+	return renderPage(cfg, filepath.Join(cfg.TemplateDir, "study.gohtml"), templateData, outputPath)
+}
 
-	// Find and organize content files
-	contentMap, err := scanContentFiles(cfg)
+// RenderExam renders the timed exam page of a quiz content file
+func RenderExam(contentType string, data *parser.ContentData, outputPath string, cfg *config.Config, nav *Nav) error {
+	templateData, err := newContentTemplateData(cfg, nav, contentType, data, fmt.Sprintf("%s - %s - Thi thử", cfg.Name, data.Label()))
 	if err != nil {
 		return err
 	}
+	templateData.protectAnswers("exam", true)
+	templateData.addBreadcrumb("Thi thử")
+
+	return renderPage(cfg, filepath.Join(cfg.TemplateDir, "exam.gohtml"), templateData, outputPath)
+}
 
+// RenderIndex generates the index page
+func RenderIndex(cfg *config.Config, nav *Nav) error {
 	// Create a content object for the index page
 	indexContent := &parser.ContentData{
 		ContentID:   "index",
@@ -129,74 +175,306 @@ func RenderIndex(cfg *config.Config) error {
 	}
 
 	// Create template data
-	templateData := &TemplateData{
-		Config:     cfg,
-		Content:    indexContent,
-		Title:      cfg.Name,
-		Timestamp:  time.Now().Format("2006-01-02 15:04:05"),
-		Menu:       cfg.Menu,
-		ContentMap: contentMap,
+	templateData, err := newTemplateData(cfg, nav, indexContent, cfg.Name)
+	if err != nil {
+		return err
+	}
+
+	indexFile := filepath.Join(cfg.TemplateDir, "index.gohtml")
+	return renderPage(cfg, indexFile, templateData, filepath.Join(cfg.OutputDir, "index.html"))
+}
+
+// RenderSearch generates the site-wide search page
+func RenderSearch(cfg *config.Config, nav *Nav) error {
+	// Create a content object for the search page
+	searchContent := &parser.ContentData{
+		ContentID:   "search",
+		ContentType: "search",
+		SourcePath:  "search",
 	}
 
-	// Parse the templates
-	tmpl, err := template.New("layout").Funcs(TemplateFunctions()).ParseFiles(layoutFile, indexFile)
+	// Create template data
+	templateData, err := newTemplateData(cfg, nav, searchContent, fmt.Sprintf("%s - Tìm kiếm", cfg.Name))
 	if err != nil {
 		return err
 	}
 
-	// Open output file
-	outFile, err := os.Create(filepath.Join(cfg.OutputDir, "index.html"))
+	searchFile := filepath.Join(cfg.TemplateDir, "search.gohtml")
+	return renderPage(cfg, searchFile, templateData, filepath.Join(cfg.OutputDir, "search.html"))
+}
+
+// RenderSection generates the index page of a section of a content type, or
+// of the whole type for the section with an empty ID, listing its files with
+// their number of rows and its subsections
+func RenderSection(cfg *config.Config, nav *Nav, contentType string, section NavItem, rowCounts map[string]int, outputPath string) error {
+	sectionContent := &parser.ContentData{
+		SourcePath:  filepath.Join(cfg.DataDir, contentType, filepath.FromSlash(section.ID)),
+		ContentType: contentType,
+		ContentID:   path.Join(section.ID, parser.IndexID),
+		Meta:        parser.Meta{Title: section.Label(), Description: section.Description},
+	}
+
+	title := fmt.Sprintf("%s - %s", cfg.Name, section.Label())
+	var templateData *TemplateData
+	var err error
+	if _, found := cfg.ContentTypes[contentType]; found {
+		templateData, err = newContentTemplateData(cfg, nav, contentType, sectionContent, title)
+	} else {
+		templateData, err = newTemplateData(cfg, nav, sectionContent, title)
+	}
 	if err != nil {
 		return err
 	}
-	defer outFile.Close()
+	templateData.Section = &section
+	templateData.RowCounts = rowCounts
 
-	// Execute the template
-	return tmpl.ExecuteTemplate(outFile, "layout", templateData)
+	return renderPage(cfg, filepath.Join(cfg.TemplateDir, "section.gohtml"), templateData, outputPath)
 }
 
-// scanContentFiles scans the data directory for content files
-func scanContentFiles(cfg *config.Config) (map[string][]string, error) {
-	contentMap := make(map[string][]string)
+// RenderRedirect writes a page sending visitors of an old path on to the
+// URL of the page now at another path, keeping the anchor of their link
+func RenderRedirect(cfg *config.Config, targetURL, outputPath string) error {
+	tmpl, err := template.ParseFiles(filepath.Join(cfg.TemplateDir, "redirect.gohtml"))
+	if err != nil {
+		return err
+	}
 
-	// Read data directory
-	dataDirs, err := os.ReadDir(cfg.DataDir)
+	var buf bytes.Buffer
+	data := struct {
+		Config *config.Config
+		URL    string
+	}{cfg, targetURL}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buf.Bytes(), 0644)
+}
+
+// RenderKanji generates the page of a kanji
+func RenderKanji(cfg *config.Config, nav *Nav, entry *kanji.Entry, outputPath string) error {
+	kanjiContent := &parser.ContentData{
+		ContentID:   entry.Kanji,
+		ContentType: config.KanjiDir,
+		SourcePath:  config.KanjiDir,
+	}
+
+	templateData, err := newTemplateData(cfg, nav, kanjiContent, fmt.Sprintf("%s - %s", entry.Kanji, cfg.Kanji.Title))
 	if err != nil {
-		return nil, err
+		return err
+	}
+	templateData.Kanji = entry
+
+	return renderPage(cfg, filepath.Join(cfg.TemplateDir, "kanji.gohtml"), templateData, outputPath)
+}
+
+// RenderKanjiIndex generates the page listing every kanji
+func RenderKanjiIndex(cfg *config.Config, nav *Nav, entries []*kanji.Entry, outputPath string) error {
+	kanjiContent := &parser.ContentData{
+		ContentID:   "index",
+		ContentType: config.KanjiDir,
+		SourcePath:  config.KanjiDir,
+	}
+
+	templateData, err := newTemplateData(cfg, nav, kanjiContent, fmt.Sprintf("%s - %s", cfg.Name, cfg.Kanji.Title))
+	if err != nil {
+		return err
 	}
+	templateData.KanjiList = entries
 
-	// Process each content type directory
-	for _, dir := range dataDirs {
-		if !dir.IsDir() {
-			continue
+	return renderPage(cfg, filepath.Join(cfg.TemplateDir, "kanji.gohtml"), templateData, outputPath)
+}
+
+// RenderTag generates the page listing the rows of a tag
+func RenderTag(cfg *config.Config, nav *Nav, tag *taxonomy.Tag, outputPath string) error {
+	tagContent := &parser.ContentData{
+		ContentID:   tag.Slug,
+		ContentType: config.TagsDir,
+		SourcePath:  config.TagsDir,
+		Meta:        parser.Meta{Title: tag.Name},
+	}
+
+	templateData, err := newTemplateData(cfg, nav, tagContent, fmt.Sprintf("%s - %s", tag.Name, cfg.Name))
+	if err != nil {
+		return err
+	}
+	templateData.Tag = tag
+
+	return renderPage(cfg, filepath.Join(cfg.TemplateDir, "tags.gohtml"), templateData, outputPath)
+}
+
+// RenderTagIndex generates the page listing every tag, next to the site
+// index rendered by RenderIndex
+func RenderTagIndex(cfg *config.Config, nav *Nav, tags []*taxonomy.Tag, outputPath string) error {
+	tagContent := &parser.ContentData{
+		ContentID:   "index",
+		ContentType: config.TagsDir,
+		SourcePath:  config.TagsDir,
+	}
+
+	templateData, err := newTemplateData(cfg, nav, tagContent, fmt.Sprintf("%s - Thẻ", cfg.Name))
+	if err != nil {
+		return err
+	}
+	templateData.TagList = tags
+
+	return renderPage(cfg, filepath.Join(cfg.TemplateDir, "tags.gohtml"), templateData, outputPath)
+}
+
+// contentTemplateFile returns the page template of a content file: the one
+// named in its metadata or else the one of its content type
+func contentTemplateFile(cfg *config.Config, contentType string, data *parser.ContentData) (string, error) {
+	// Get the content type configuration
+	contentTypeConfig, found := cfg.ContentTypes[contentType]
+	if !found {
+		return "", fmt.Errorf("content type configuration not found: %s", contentType)
+	}
+	
+	// Determine template name from content type config or fallback to content type
+	templateName := contentTypeConfig.Template
+	if templateName == "" {
+		templateName = contentType
+	}
+	if data.Meta.Template != "" {
+		templateName = data.Meta.Template
+	}
+
+	// Select template file
+	templateFile := filepath.Join(cfg.TemplateDir, templateName+".gohtml")
+	
+	// Check if the template file exists
+	if _, err := os.Stat(templateFile); os.IsNotExist(err) {
+		fmt.Printf("Template file %s not found, falling back to default\n", templateFile)
+		templateFile = filepath.Join(cfg.TemplateDir, "default.gohtml")
+		// If default template doesn't exist either, return an error
+		if _, err := os.Stat(templateFile); os.IsNotExist(err) {
+			return "", fmt.Errorf("no template found for content type: %s and default template missing", contentType)
 		}
+	}
+
+	return templateFile, nil
+}
+
+// newTemplateData creates the template data shared by every page
+func newTemplateData(cfg *config.Config, nav *Nav, data *parser.ContentData, title string) (*TemplateData, error) {
+	return &TemplateData{
+		Config:      cfg,
+		Content:     data,
+		Title:       title,
+		Meta:        data.Meta,
+		Timestamp:   time.Now().Format("2006-01-02 15:04:05"),
+		Menu:        nav.Menu,
+		ContentMap:  nav.ContentMap,
+		Breadcrumbs: breadcrumbs(cfg, nav.ContentMap, data),
+	}, nil
+}
+
+// newContentTemplateData creates the template data for a page about content
+// of a configured content type
+func newContentTemplateData(cfg *config.Config, nav *Nav, contentType string, data *parser.ContentData, title string) (*TemplateData, error) {
+	contentTypeConfig, found := cfg.ContentTypes[contentType]
+	if !found {
+		return nil, fmt.Errorf("content type configuration not found: %s", contentType)
+	}
+
+	templateData, err := newTemplateData(cfg, nav, data, title)
+	if err != nil {
+		return nil, err
+	}
+	templateData.ContentTypeConfig = &contentTypeConfig
+
+	templateData.password, err = cfg.Password(contentType, data.ContentID)
+	if err != nil {
+		return nil, err
+	}
+	templateData.LockID = contentType + "/" + data.ContentID
+	return templateData, nil
+}
+
+// renderPage renders a page template inside the layout to outputPath. A
+// password-protected page is encrypted and written as its unlock page.
+func renderPage(cfg *config.Config, templateFile string, templateData *TemplateData, outputPath string) error {
+	// The last breadcrumb is the page itself
+	if last := len(templateData.Breadcrumbs) - 1; last >= 0 {
+		templateData.Breadcrumbs[last].URL = ""
+	}
 
-		contentType := dir.Name()
-		files := []string{}
+	var buf bytes.Buffer
+	if err := executePage(cfg, templateFile, templateData, &buf); err != nil {
+		return err
+	}
 
-		// Read content files
-		contentFiles, err := os.ReadDir(filepath.Join(cfg.DataDir, contentType))
+	if templateData.password != "" {
+		page, err := media.Embed(cfg, buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to embed media in %s: %w", outputPath, err)
+		}
+		envelope, err := password.Encrypt(templateData.password, page)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("failed to encrypt %s: %w", outputPath, err)
 		}
 
-		for _, file := range contentFiles {
-			if file.IsDir() {
-				continue
-			}
-
-			// Only include supported file types
-			ext := filepath.Ext(file.Name())
-			if ext == ".csv" || ext == ".json" {
-				baseName := file.Name()[:len(file.Name())-len(ext)]
-				files = append(files, baseName)
-			}
+		lockedData := *templateData
+		lockedData.Locked = envelope
+		buf.Reset()
+		if err := executePage(cfg, filepath.Join(cfg.TemplateDir, "locked.gohtml"), &lockedData, &buf); err != nil {
+			return err
 		}
+	}
 
-		contentMap[contentType] = files
+	// Create output directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
 	}
 
-	return contentMap, nil
+	return os.WriteFile(outputPath, buf.Bytes(), 0644)
+}
+
+// executePage executes a page template inside the layout into w
+func executePage(cfg *config.Config, templateFile string, templateData *TemplateData, w io.Writer) error {
+	layoutFile := filepath.Join(cfg.TemplateDir, "layout.gohtml")
+
+	// Check if the template files exist
+	if _, err := os.Stat(templateFile); os.IsNotExist(err) {
+		return fmt.Errorf("template not found: %s", templateFile)
+	}
+	if _, err := os.Stat(layoutFile); os.IsNotExist(err) {
+		return fmt.Errorf("layout template not found: %s", layoutFile)
+	}
+
+	fmt.Printf("Using template files: %s and %s\n", layoutFile, templateFile)
+
+	// Parse the templates, including shared partials
+	files := []string{layoutFile, templateFile}
+	partials, err := filepath.Glob(filepath.Join(cfg.TemplateDir, "partials", "*.gohtml"))
+	if err != nil {
+		return err
+	}
+	files = append(files, partials...)
+
+	tmpl, err := template.New("layout").Funcs(TemplateFunctions()).ParseFiles(files...)
+	if err != nil {
+		return err
+	}
+	tmpl.Funcs(template.FuncMap{"include": includeFunc(tmpl)})
+
+	// Execute the template
+	return tmpl.ExecuteTemplate(w, "layout", templateData)
+}
+
+// includeFunc returns the include template function, which renders a
+// named template of the page into a value so it can be processed further
+func includeFunc(tmpl *template.Template) func(name string, data interface{}) (template.HTML, error) {
+	return func(name string, data interface{}) (template.HTML, error) {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", err
+		}
+		return template.HTML(buf.String()), nil
+	}
 }
 
 // RenderString renders a template string with the given data
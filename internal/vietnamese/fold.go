@@ -0,0 +1,50 @@
+package vietnamese
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accented lists the precomposed Vietnamese letters for each base letter
+var accented = map[rune]string{
+	'a': "àáảãạăằắẳẵặâầấẩẫậ",
+	'e': "èéẻẽẹêềếểễệ",
+	'i': "ìíỉĩị",
+	'o': "òóỏõọôồốổỗộơờớởỡợ",
+	'u': "ùúủũụưừứửữự",
+	'y': "ỳýỷỹỵ",
+	'd': "đ",
+}
+
+// baseLetter maps every accented letter to its plain lower-case letter
+var baseLetter = buildBaseLetter()
+
+func buildBaseLetter() map[rune]rune {
+	m := make(map[rune]rune)
+	for base, letters := range accented {
+		for _, r := range letters {
+			m[r] = base
+			m[unicode.ToUpper(r)] = base
+		}
+	}
+	return m
+}
+
+// Fold lower-cases s and strips Vietnamese diacritics, so "Đáp án" and
+// "dap an" compare equal. Both precomposed letters and letters followed by
+// combining marks are handled.
+func Fold(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if r >= 0x0300 && r <= 0x036F {
+			continue
+		}
+		if base, ok := baseLetter[r]; ok {
+			b.WriteRune(base)
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
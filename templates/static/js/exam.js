@@ -0,0 +1,328 @@
+/**
+ * Timed exam page functionality
+ *
+ * Option selection (and immediate feedback when show_result_immediately is
+ * set) comes from nguphap.js; this file adds question drawing, shuffling,
+ * the timer, the single submit and the score summary.
+ */
+document.addEventListener("DOMContentLoaded", () => {
+    initExam();
+});
+
+/**
+ * Initialize the exam page
+ */
+function initExam() {
+    const container = document.querySelector(".exam-container");
+    const startBtn = document.getElementById("exam-start");
+    if (!container || !startBtn) return;
+
+    const dataset = container.dataset;
+    const settings = {
+        timeLimit: parseInt(dataset.timeLimit || "0", 10),
+        questionCount: parseInt(dataset.questionCount || "0", 10),
+        shuffle: dataset.shuffle === "true",
+        shuffleOptions: dataset.shuffleOptions === "true",
+        highlightCorrect: dataset.highlightCorrect !== "false",
+    };
+
+    startBtn.addEventListener("click", () => {
+        startExam(container, settings);
+    });
+
+    const retryBtn = document.getElementById("exam-retry");
+    if (retryBtn) {
+        retryBtn.addEventListener("click", () => {
+            window.location.reload();
+        });
+    }
+}
+
+/**
+ * Draw the questions for this attempt and start the timer
+ * @param {HTMLElement} container - The exam container
+ * @param {Object} settings - Exam settings
+ */
+function startExam(container, settings) {
+    // Questions on a reading passage are shuffled and drawn with it
+    const units = Array.from(container.children).filter((el) =>
+        el.matches(".question-group, .grammar-question")
+    );
+    if (settings.shuffle) shuffleArray(units);
+
+    const limit =
+        settings.questionCount > 0 ? settings.questionCount : Infinity;
+    let questions = [];
+    units.forEach((unit) => {
+        const isGroup = unit.classList.contains("question-group");
+        let unitQuestions = isGroup
+            ? Array.from(unit.querySelectorAll(".grammar-question"))
+            : [unit];
+        if (settings.shuffle) shuffleArray(unitQuestions);
+        unitQuestions = unitQuestions.slice(0, limit - questions.length);
+
+        // Hide the questions not drawn and put the drawn ones in order
+        unit.classList.toggle("hidden", unitQuestions.length === 0);
+        container.appendChild(unit);
+        if (isGroup) {
+            unit.querySelectorAll(".grammar-question").forEach(
+                (question) => question.classList.add("hidden")
+            );
+            unitQuestions.forEach((question) => {
+                question.classList.remove("hidden");
+                unit.appendChild(question);
+            });
+        }
+        questions = questions.concat(unitQuestions);
+    });
+
+    questions.forEach((question, index) => {
+        addQuestionBadge(question, index + 1);
+        if (settings.shuffleOptions) shuffleOptions(question);
+    });
+
+    document.getElementById("exam-intro").classList.add("hidden");
+    document.getElementById("exam-status").classList.remove("hidden");
+    document.getElementById("exam-actions").classList.remove("hidden");
+    container.classList.remove("hidden");
+
+    const exam = {
+        container,
+        settings,
+        questions,
+        startedAt: Date.now(),
+        timer: null,
+        submitted: false,
+    };
+
+    // Track progress after nguphap.js has handled the selection
+    container.addEventListener("click", () => updateProgress(exam));
+    container.addEventListener("input", () => updateProgress(exam));
+    container.addEventListener("change", () => updateProgress(exam));
+
+    document
+        .getElementById("exam-submit")
+        .addEventListener("click", () => submitExam(exam, false));
+
+    updateTimer(exam);
+    exam.timer = setInterval(() => updateTimer(exam), 1000);
+}
+
+/**
+ * Update the timer, submitting automatically when time is up
+ * @param {Object} exam - Exam state
+ */
+function updateTimer(exam) {
+    const timerEl = document.getElementById("exam-timer");
+    const elapsed = Math.floor((Date.now() - exam.startedAt) / 1000);
+
+    if (exam.settings.timeLimit > 0) {
+        const remaining = Math.max(0, exam.settings.timeLimit - elapsed);
+        timerEl.textContent = formatDuration(remaining);
+        timerEl.classList.toggle("exam-timer-warning", remaining <= 60);
+        if (remaining === 0) submitExam(exam, true);
+    } else {
+        timerEl.textContent = formatDuration(elapsed);
+    }
+}
+
+/**
+ * Update the answered counter
+ * @param {Object} exam - Exam state
+ */
+function updateProgress(exam) {
+    const answered = exam.questions.filter((question) =>
+        questionHandler(question).isAnswered(question)
+    ).length;
+    document.getElementById("exam-answered").textContent = answered;
+}
+
+/**
+ * Grade every drawn question and show the summary
+ * @param {Object} exam - Exam state
+ * @param {boolean} timeUp - Whether the time limit ran out
+ */
+async function submitExam(exam, timeUp) {
+    if (exam.submitted) return;
+
+    const unanswered = exam.questions.filter(
+        (question) => !questionHandler(question).isAnswered(question)
+    ).length;
+    if (
+        !timeUp &&
+        unanswered > 0 &&
+        !window.confirm(
+            `Còn ${unanswered} câu chưa trả lời. Bạn có chắc muốn nộp bài?`
+        )
+    ) {
+        return;
+    }
+
+    exam.submitted = true;
+    clearInterval(exam.timer);
+
+    // Protected pages only decrypt the answers now
+    await Promise.all(exam.questions.map(ensureAnswers));
+
+    const results = exam.questions.map((question, index) =>
+        reviewQuestion(question, index + 1, exam.settings.highlightCorrect)
+    );
+    const correct = results.filter((result) => result.correct).length;
+    const elapsed = Math.floor((Date.now() - exam.startedAt) / 1000);
+
+    renderSummary(results, correct, elapsed, timeUp, exam.settings);
+
+    document.getElementById("exam-actions").classList.add("hidden");
+    exam.container.classList.add("exam-submitted");
+}
+
+/**
+ * Lock a question and show whether it was answered correctly
+ * @param {HTMLElement} question - The question container
+ * @param {number} number - Question number in this attempt
+ * @param {boolean} highlightCorrect - Whether to reveal the correct answer
+ * @returns {Object} - Review result for the summary
+ */
+function reviewQuestion(question, number, highlightCorrect) {
+    const handler = questionHandler(question);
+    const answered = handler.isAnswered(question);
+    const isCorrect = answered && handler.grade(question);
+    const answerText = question.querySelector(
+        ".answer-result .answer-text"
+    );
+
+    question.classList.add("answered");
+    handler.lock(question);
+    if (answered || highlightCorrect) {
+        handler.mark(question, highlightCorrect);
+    }
+
+    if (highlightCorrect) {
+        question
+            .querySelector(".answer-result")
+            .classList.remove("hidden");
+        showExplanation(question, false);
+    }
+
+    question.classList.remove("answered-correct", "answered-incorrect");
+    question.classList.add(
+        isCorrect ? "answered-correct" : "answered-incorrect"
+    );
+
+    return {
+        id: question.id,
+        number,
+        correct: isCorrect,
+        selected: answered ? handler.response(question) : "",
+        answer: answerText ? answerText.textContent.trim() : "",
+    };
+}
+
+/**
+ * Render the score summary with a per-question review
+ * @param {Object[]} results - Review results
+ * @param {number} correct - Number of correct answers
+ * @param {number} elapsed - Seconds spent
+ * @param {boolean} timeUp - Whether the time limit ran out
+ * @param {Object} settings - Exam settings
+ */
+function renderSummary(results, correct, elapsed, timeUp, settings) {
+    const summary = document.getElementById("exam-summary");
+    const percent = results.length
+        ? Math.round((correct / results.length) * 100)
+        : 0;
+
+    summary.querySelector(".exam-score").textContent =
+        `Kết quả: ${correct}/${results.length} (${percent}%)`;
+    summary.querySelector(".exam-meta").textContent =
+        (timeUp ? "Hết giờ. " : "") +
+        `Thời gian làm bài: ${formatDuration(elapsed)}`;
+
+    const review = summary.querySelector(".exam-review");
+    review.innerHTML = "";
+    results.forEach((result) => {
+        const item = document.createElement("li");
+        item.className = result.correct
+            ? "exam-review-item correct"
+            : "exam-review-item incorrect";
+
+        const link = document.createElement("a");
+        link.href = `#${result.id}`;
+        link.textContent = `Câu ${result.number}: ${
+            result.correct ? "✓" : "✗"
+        }`;
+        item.appendChild(link);
+
+        const detail = document.createElement("span");
+        let text = result.selected
+            ? ` Bạn chọn: ${result.selected}`
+            : " Chưa trả lời";
+        if (!result.correct && settings.highlightCorrect) {
+            text += ` · Đáp án: ${result.answer}`;
+        }
+        detail.textContent = text;
+        item.appendChild(detail);
+
+        review.appendChild(item);
+    });
+
+    summary.classList.remove("hidden");
+    summary.scrollIntoView({ behavior: "smooth" });
+}
+
+/**
+ * Show the question number in front of the question
+ * @param {HTMLElement} question - The question container
+ * @param {number} number - Question number in this attempt
+ */
+function addQuestionBadge(question, number) {
+    const header = question.querySelector(".question-header");
+    let badge = header.querySelector(".question-badge");
+    if (!badge) {
+        badge = document.createElement("span");
+        badge.className = "question-badge";
+        header.prepend(badge);
+    }
+    badge.textContent = number;
+}
+
+/**
+ * Shuffle the answer options (or the pieces or pairs) of a question in place
+ * @param {HTMLElement} question - The question container
+ */
+function shuffleOptions(question) {
+    const list = question.querySelector(
+        ".answer-options, .order-pool, .match-list"
+    );
+    if (!list) return;
+    shuffleArray(Array.from(list.children)).forEach((option) =>
+        list.appendChild(option)
+    );
+}
+
+/**
+ * Shuffle an array in place (Fisher-Yates)
+ * @param {Array} array - The array to shuffle
+ * @returns {Array} - The same array
+ */
+function shuffleArray(array) {
+    for (let i = array.length - 1; i > 0; i--) {
+        const j = Math.floor(Math.random() * (i + 1));
+        [array[i], array[j]] = [array[j], array[i]];
+    }
+    return array;
+}
+
+/**
+ * Format seconds as mm:ss
+ * @param {number} seconds - Duration in seconds
+ * @returns {string} - Formatted duration
+ */
+function formatDuration(seconds) {
+    const minutes = Math.floor(seconds / 60);
+    const rest = seconds % 60;
+    return `${String(minutes).padStart(2, "0")}:${String(rest).padStart(
+        2,
+        "0"
+    )}`;
+}
@@ -0,0 +1,71 @@
+/**
+ * Unlock page of a password-protected page
+ *
+ * The whole page was encrypted at build time with a key derived from the
+ * password (PBKDF2-SHA-256, AES-GCM). Once decrypted it replaces this
+ * document. The password is kept for the browser session so the other
+ * pages of the same file (exam, study, test forms) open without asking.
+ */
+document.addEventListener("DOMContentLoaded", () => {
+    initLocked();
+});
+
+/**
+ * Try the remembered password, then wait for the form
+ */
+async function initLocked() {
+    const locked = document.getElementById("locked");
+    const form = document.getElementById("locked-form");
+    if (!locked || !form) return;
+
+    const remembered = sessionStorage.getItem(
+        window.captoc.passwordKey(locked.dataset.lockId)
+    );
+    if (remembered && (await unlockPage(locked, remembered))) return;
+
+    form.addEventListener("submit", async (e) => {
+        e.preventDefault();
+        const input = document.getElementById("locked-password");
+        setMessage("Đang mở khóa...");
+        if (!(await unlockPage(locked, input.value))) {
+            setMessage("Sai mật khẩu.");
+            input.select();
+        }
+    });
+}
+
+/**
+ * Decrypt the page and replace the document with it
+ * @param {HTMLElement} locked - Element carrying the encrypted page
+ * @param {string} password - Entered password
+ * @returns {Promise<boolean>} - False if the password is wrong
+ */
+async function unlockPage(locked, password) {
+    let html;
+    try {
+        html = await window.captoc.decryptWithPassword(
+            locked.dataset,
+            password
+        );
+    } catch (e) {
+        return false;
+    }
+
+    sessionStorage.setItem(
+        window.captoc.passwordKey(locked.dataset.lockId),
+        password
+    );
+    document.open();
+    document.write(html);
+    document.close();
+    return true;
+}
+
+/**
+ * Show a status message under the form
+ * @param {string} message - Message text
+ */
+function setMessage(message) {
+    const el = document.getElementById("locked-message");
+    if (el) el.textContent = message;
+}
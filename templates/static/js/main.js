@@ -11,15 +11,22 @@ document.addEventListener("DOMContentLoaded", () => {
     initDropdowns();
     setupActiveLinks();
     setupGenericContentInteractions();
-
-    // Register utility functions globally
-    window.captoc = {
-        toggleVisibility,
-        searchContent,
-        parseOptions,
-    };
+    initTagFilter();
 });
 
+// Register utility functions globally, before any DOMContentLoaded handler
+// of the page scripts runs
+window.captoc = {
+    toggleVisibility,
+    searchContent,
+    parseOptions,
+    normalizeText,
+    fromBase64,
+    decryptText,
+    decryptWithPassword,
+    passwordKey,
+};
+
 /**
  * Initialize theme toggle (light/dark mode)
  */
@@ -251,6 +258,13 @@ function setupActiveLinks() {
                 if (section) {
                     section.classList.add("active");
                 }
+
+                // Open the collapsed groups of the link's sections
+                let group = link.closest("details");
+                while (group) {
+                    group.open = true;
+                    group = group.parentElement.closest("details");
+                }
             }
         });
 
@@ -274,6 +288,45 @@ function setupActiveLinks() {
         });
 }
 
+/**
+ * Filter the rows of a content page by the tag chips above them. Rows carry
+ * their tag slugs in data-tags; question groups are hidden when none of
+ * their questions is shown.
+ */
+function initTagFilter() {
+    const filter = document.querySelector(".tag-filter");
+    if (!filter) {
+        return;
+    }
+
+    const chips = filter.querySelectorAll(".tag-chip");
+    chips.forEach((chip) => {
+        chip.addEventListener("click", () => {
+            const tag = chip.dataset.tag;
+            chips.forEach((other) =>
+                other.classList.toggle("active", other === chip)
+            );
+
+            document.querySelectorAll("[data-id]").forEach((row) => {
+                const tags = (row.dataset.tags || "").split(" ");
+                row.classList.toggle(
+                    "tag-hidden",
+                    tag !== "" && !tags.includes(tag)
+                );
+            });
+
+            document
+                .querySelectorAll(".question-group")
+                .forEach((group) => {
+                    const shown = group.querySelector(
+                        "[data-id]:not(.tag-hidden)"
+                    );
+                    group.classList.toggle("tag-hidden", !shown);
+                });
+        });
+    });
+}
+
 /**
  * Utility function to toggle element visibility
  */
@@ -298,6 +351,95 @@ function parseOptions(optionsStr) {
     }
 }
 
+/**
+ * Normalize text for comparison: NFKC width folding, lower case,
+ * and katakana folded to hiragana
+ */
+function normalizeText(text) {
+    return (text || "")
+        .normalize("NFKC")
+        .toLowerCase()
+        .replace(/[\u30a1-\u30f6]/g, (ch) =>
+            String.fromCharCode(ch.charCodeAt(0) - 0x60)
+        )
+        .trim();
+}
+
+/**
+ * Decode base64 into bytes
+ * @param {string} value - Base64 text
+ * @returns {Uint8Array} - Decoded bytes
+ */
+function fromBase64(value) {
+    return Uint8Array.from(atob(value), (c) => c.charCodeAt(0));
+}
+
+/**
+ * Decrypt text sealed by the build with AES-GCM (nonce followed by the
+ * ciphertext, base64 encoded)
+ * @param {Uint8Array} keyBytes - Raw AES key
+ * @param {string} sealed - Base64 nonce and ciphertext
+ * @returns {Promise<string>} - The decrypted text
+ */
+async function decryptText(keyBytes, sealed) {
+    if (!window.crypto || !window.crypto.subtle) {
+        throw new Error("Web Crypto is not available");
+    }
+    const bytes = fromBase64(sealed);
+    const key = await crypto.subtle.importKey(
+        "raw",
+        keyBytes,
+        "AES-GCM",
+        false,
+        ["decrypt"]
+    );
+    const plaintext = await crypto.subtle.decrypt(
+        { name: "AES-GCM", iv: bytes.slice(0, 12) },
+        key,
+        bytes.slice(12)
+    );
+    return new TextDecoder().decode(plaintext);
+}
+
+/**
+ * sessionStorage key of the password entered for a protected content file.
+ * The unlock page and the decrypted page share one global scope, so this
+ * lives here rather than in a constant of each script.
+ * @param {string} lockId - Content type and content ID
+ * @returns {string} - Storage key
+ */
+function passwordKey(lockId) {
+    return `captoc-password:${lockId}`;
+}
+
+/**
+ * Decrypt an envelope encrypted by the build with a password
+ * (PBKDF2-SHA-256 key, AES-GCM)
+ * @param {{salt: string, iterations: (string|number), data: string}} envelope
+ * @param {string} password - Password
+ * @returns {Promise<string>} - The decrypted text
+ */
+async function decryptWithPassword(envelope, password) {
+    const material = await crypto.subtle.importKey(
+        "raw",
+        new TextEncoder().encode(password),
+        "PBKDF2",
+        false,
+        ["deriveBits"]
+    );
+    const bits = await crypto.subtle.deriveBits(
+        {
+            name: "PBKDF2",
+            hash: "SHA-256",
+            salt: fromBase64(envelope.salt),
+            iterations: parseInt(envelope.iterations, 10),
+        },
+        material,
+        256
+    );
+    return decryptText(new Uint8Array(bits), envelope.data);
+}
+
 /**
  * Utility function for search in content
  */
@@ -307,10 +449,10 @@ function searchContent(
     getSearchableText,
     displayItem
 ) {
-    searchText = searchText.toLowerCase();
+    searchText = normalizeText(searchText);
 
     items.forEach((item) => {
-        const text = getSearchableText(item).toLowerCase();
+        const text = normalizeText(getSearchableText(item));
         const visible = text.includes(searchText);
         displayItem(item, visible);
     });
@@ -1,5 +1,9 @@
 /**
  * Grammar (nguphap) page functionality
+ *
+ * Each question type (data-question-type) has a handler that wires up its
+ * inputs and knows how to grade, mark, reveal and reset the question. The
+ * exam page reuses the same handlers through questionHandler().
  */
 document.addEventListener("DOMContentLoaded", () => {
     initQuestions();
@@ -19,55 +23,12 @@ function initQuestions() {
         question.style.animationDelay = `${index * 100}ms`;
         question.classList.add("animate-in");
 
-        // Handle option selection by clicking the entire answer option div
-        question
-            .querySelectorAll(".answer-option")
-            .forEach((option) => {
-                option.addEventListener(
-                    "click",
-                    function () {
-                        // Skip if already answered
-                        if (
-                            question.classList.contains(
-                                "answered"
-                            )
-                        )
-                            return;
-
-                        // Select the option
-                        const radio =
-                            this.querySelector(
-                                ".option-radio"
-                            );
-                        if (radio && !radio.disabled) {
-                            // Uncheck all other options
-                            question
-                                .querySelectorAll(
-                                    ".option-radio"
-                                )
-                                .forEach((r) => {
-                                    r.checked = false;
-                                });
-
-                            // Check this one
-                            radio.checked = true;
-
-                            // Update visual selection
-                            question
-                                .querySelectorAll(
-                                    ".answer-option"
-                                )
-                                .forEach((opt) => {
-                                    opt.classList.remove(
-                                        "selected"
-                                    );
-                                });
-
-                            this.classList.add("selected");
-                        }
-                    }
-                );
-            });
+        // Check right away once fully answered when configured
+        questionHandler(question).init(question, () => {
+            if (getQuizSettings().showResultImmediately) {
+                checkAnswer(question);
+            }
+        });
 
         // Check answer button
         const checkBtn = question.querySelector(
@@ -85,95 +46,96 @@ function initQuestions() {
  * Check answer for a question
  * @param {HTMLElement} question - The question container
  */
-function checkAnswer(question) {
-    const radio = question.querySelector(
-        ".option-radio:checked"
-    );
+async function checkAnswer(question) {
+    if (question.classList.contains("answered")) return;
 
-    if (radio) {
-        // Mark question as answered
-        question.classList.add("answered");
+    const handler = questionHandler(question);
+    if (!handler.isAnswered(question)) {
+        showAlert(question, handler.prompt);
+        return;
+    }
 
-        // Get selected option element
-        const selectedOption = radio.closest(
-            ".answer-option"
-        );
-        const isCorrect =
-            selectedOption.classList.contains("correct");
+    if (!(await ensureAnswers(question))) return;
+    if (question.classList.contains("answered")) return;
 
-        // Disable all options
-        question
-            .querySelectorAll(".option-radio")
-            .forEach((opt) => {
-                opt.disabled = true;
-            });
+    // Mark question as answered
+    question.classList.add("answered");
+    const isCorrect = handler.grade(question);
+    handler.lock(question);
 
-        // Show correct/incorrect highlights
-        question
-            .querySelectorAll(".answer-option")
-            .forEach((opt) => {
-                if (opt.classList.contains("correct")) {
-                    opt.classList.add("highlight-correct");
-                } else if (
-                    opt.querySelector(".option-radio")
-                        .checked
-                ) {
-                    if (
-                        !opt.classList.contains("correct")
-                    ) {
-                        opt.classList.add(
-                            "highlight-incorrect"
-                        );
-                    }
-                }
-            });
+    // Show correct/incorrect highlights. Without highlight_correct only the
+    // given answer is marked, so the correct one is not revealed.
+    const { highlightCorrect } = getQuizSettings();
+    handler.mark(question, highlightCorrect);
 
-        // Show result
+    // Show result
+    if (highlightCorrect) {
         const resultDiv = question.querySelector(
             ".answer-result"
         );
         resultDiv.classList.remove("hidden");
         resultDiv.classList.add("fade-in");
+        showExplanation(question, false);
+    }
 
-        // Update button
-        const checkBtn = question.querySelector(
-            ".check-answer-btn"
-        );
+    // Update button
+    const checkBtn = question.querySelector(
+        ".check-answer-btn"
+    );
+    if (checkBtn) {
         checkBtn.textContent = "Đã kiểm tra";
         checkBtn.disabled = true;
+    }
 
-        // Add result class
-        if (isCorrect) {
-            question.classList.add("answered-correct");
-        } else {
-            question.classList.add("answered-incorrect");
-        }
+    // Add result class
+    if (isCorrect) {
+        question.classList.add("answered-correct");
     } else {
-        // Show alert if nothing selected
-        const alert = document.createElement("div");
-        alert.className = "alert alert-warning";
-        alert.textContent = "Vui lòng chọn một đáp án";
-
-        // Remove existing alerts
-        const existingAlert =
-            question.querySelector(".alert");
-        if (existingAlert) {
-            question.removeChild(existingAlert);
-        }
+        question.classList.add("answered-incorrect");
+    }
+}
+
+/**
+ * Show the explanation of a question if its mode allows it
+ * @param {HTMLElement} question - The question container
+ * @param {boolean} revealAll - Whether all answers are being revealed
+ */
+function showExplanation(question, revealAll) {
+    const explanation = question.querySelector(".answer-explanation");
+    if (!explanation) return;
+    if (revealAll || explanation.dataset.show === "check") {
+        explanation.classList.remove("hidden");
+    }
+}
 
-        question.appendChild(alert);
+/**
+ * Show a short-lived warning inside a question
+ * @param {HTMLElement} question - The question container
+ * @param {string} message - Warning text
+ */
+function showAlert(question, message) {
+    const alert = document.createElement("div");
+    alert.className = "alert alert-warning";
+    alert.textContent = message;
+
+    // Remove existing alerts
+    const existingAlert = question.querySelector(".alert");
+    if (existingAlert) {
+        question.removeChild(existingAlert);
+    }
+
+    question.appendChild(alert);
+    setTimeout(() => {
+        alert.classList.add("show");
         setTimeout(() => {
-            alert.classList.add("show");
+            alert.classList.remove("show");
             setTimeout(() => {
-                alert.classList.remove("show");
-                setTimeout(() => {
-                    if (question.contains(alert)) {
-                        question.removeChild(alert);
-                    }
-                }, 300);
-            }, 2000);
-        }, 10);
-    }
+                if (question.contains(alert)) {
+                    question.removeChild(alert);
+                }
+            }, 300);
+        }, 2000);
+    }, 10);
 }
 
 /**
@@ -193,52 +155,32 @@ function initControlButtons() {
     // Show all answers
     if (showAllBtn) {
         showAllBtn.addEventListener("click", () => {
-            questions.forEach((question) => {
+            questions.forEach(async (question) => {
                 // Skip already answered questions
                 if (question.classList.contains("answered"))
                     return;
+                if (!(await ensureAnswers(question))) return;
 
-                // Highlight correct answers
-                question
-                    .querySelectorAll(".answer-option")
-                    .forEach((opt) => {
-                        if (
-                            opt.classList.contains(
-                                "correct"
-                            )
-                        ) {
-                            opt.classList.add(
-                                "highlight-correct"
-                            );
-
-                            // Check the correct radio button
-                            const radio =
-                                opt.querySelector(
-                                    ".option-radio"
-                                );
-                            if (radio) radio.checked = true;
-                        }
-                    });
+                const handler = questionHandler(question);
+                handler.reveal(question);
+                handler.lock(question);
+                handler.mark(question, true);
 
                 // Show result
                 const resultDiv = question.querySelector(
                     ".answer-result"
                 );
                 resultDiv.classList.remove("hidden");
-
-                // Disable options
-                question
-                    .querySelectorAll(".option-radio")
-                    .forEach((opt) => {
-                        opt.disabled = true;
-                    });
+                showExplanation(question, true);
 
                 // Update button
                 const checkBtn = question.querySelector(
                     ".check-answer-btn"
                 );
-                checkBtn.textContent = "Đã kiểm tra";
-                checkBtn.disabled = true;
+                if (checkBtn) {
+                    checkBtn.textContent = "Đã kiểm tra";
+                    checkBtn.disabled = true;
+                }
 
                 // Mark as answered
                 question.classList.add("answered");
@@ -250,37 +192,26 @@ function initControlButtons() {
     if (hideAllBtn) {
         hideAllBtn.addEventListener("click", () => {
             questions.forEach((question) => {
-                // Reset options
-                question
-                    .querySelectorAll(".option-radio")
-                    .forEach((opt) => {
-                        opt.disabled = false;
-                        opt.checked = false;
-                    });
-
-                // Clear highlights
-                question
-                    .querySelectorAll(".answer-option")
-                    .forEach((opt) => {
-                        opt.classList.remove(
-                            "highlight-correct",
-                            "highlight-incorrect",
-                            "selected"
-                        );
-                    });
+                questionHandler(question).reset(question);
 
                 // Hide result
                 const resultDiv = question.querySelector(
                     ".answer-result"
                 );
                 resultDiv.classList.add("hidden");
+                const explanation = question.querySelector(
+                    ".answer-explanation"
+                );
+                if (explanation) explanation.classList.add("hidden");
 
                 // Reset button
                 const checkBtn = question.querySelector(
                     ".check-answer-btn"
                 );
-                checkBtn.textContent = "Kiểm tra đáp án";
-                checkBtn.disabled = false;
+                if (checkBtn) {
+                    checkBtn.textContent = "Kiểm tra đáp án";
+                    checkBtn.disabled = false;
+                }
 
                 // Remove answered state
                 question.classList.remove(
@@ -293,6 +224,562 @@ function initControlButtons() {
     }
 }
 
+/**
+ * Make sure the answers of a question are in the page. Protected pages
+ * carry them encrypted in data-answers and they are only decrypted and
+ * filled in when the question is checked or revealed.
+ * @param {HTMLElement} question - The question container
+ * @returns {Promise<boolean>} - False if the answers could not be decrypted
+ */
+function ensureAnswers(question) {
+    if (!question.dataset.answers) return Promise.resolve(true);
+
+    if (!question.answersLoaded) {
+        const container = question.closest("[data-answer-key]");
+        question.answersLoaded = window.captoc
+            .decryptText(
+                window.captoc.fromBase64(container.dataset.answerKey),
+                question.dataset.answers
+            )
+            .then((text) => {
+                fillAnswers(question, JSON.parse(text));
+                delete question.dataset.answers;
+                return true;
+            })
+            .catch(() => {
+                question.answersLoaded = null;
+                showAlert(question, "Không giải mã được đáp án");
+                return false;
+            });
+    }
+    return question.answersLoaded;
+}
+
+/**
+ * Put decrypted answers into the markup the question handlers read
+ * @param {HTMLElement} question - The question container
+ * @param {Object} answers - Decrypted answer data
+ */
+function fillAnswers(question, answers) {
+    const correct = answers.correct || [];
+    question
+        .querySelectorAll(".answer-option")
+        .forEach((opt) => {
+            if (correct.includes(parseInt(opt.dataset.option, 10))) {
+                opt.classList.add("correct");
+            }
+        });
+
+    const textBox = question.querySelector(".text-answer-box");
+    if (textBox) {
+        textBox.dataset.accepted = JSON.stringify(answers.accepted || []);
+    }
+
+    const order = question.querySelector(".order-question");
+    if (order) {
+        order.dataset.answer = JSON.stringify(answers.order || []);
+    }
+
+    const matches = answers.matches || [];
+    question.querySelectorAll(".match-row").forEach((row) => {
+        row.dataset.answer = matches[parseInt(row.dataset.pair, 10)] || "";
+    });
+
+    question.querySelector(".answer-result").innerHTML = answers.result;
+}
+
+/**
+ * Get the handler for the type of a question
+ * @param {HTMLElement} question - The question container
+ * @returns {Object} - Question type handler
+ */
+function questionHandler(question) {
+    return (
+        questionTypes[question.dataset.questionType] ||
+        questionTypes.single
+    );
+}
+
+/**
+ * Remove the result highlights inside a question
+ * @param {HTMLElement} question - The question container
+ */
+function clearHighlights(question) {
+    question
+        .querySelectorAll(".highlight-correct, .highlight-incorrect")
+        .forEach((el) => {
+            el.classList.remove(
+                "highlight-correct",
+                "highlight-incorrect"
+            );
+        });
+}
+
+/**
+ * Normalize a typed or displayed answer for comparison
+ * @param {string} text - Answer text
+ * @returns {string} - Comparable form
+ */
+function answerKey(text) {
+    return window.captoc
+        .normalizeText(text || "")
+        .replace(/\s+/g, " ")
+        .trim();
+}
+
+/**
+ * Parse a JSON list stored in a data attribute
+ * @param {string} value - Attribute value
+ * @returns {string[]} - The list, empty if invalid
+ */
+function parseList(value) {
+    try {
+        return JSON.parse(value || "[]");
+    } catch (e) {
+        return [];
+    }
+}
+
+/**
+ * Handler for single and multiple choice questions. Correct options carry
+ * the "correct" class; multiple choice questions use checkboxes and are
+ * correct when exactly the correct options are selected.
+ */
+const choiceHandler = {
+    prompt: "Vui lòng chọn một đáp án",
+
+    init(question, onComplete) {
+        // Handle option selection by clicking the entire answer option div
+        question
+            .querySelectorAll(".answer-option")
+            .forEach((option) => {
+                option.addEventListener("click", () => {
+                    // Skip if already answered
+                    if (question.classList.contains("answered"))
+                        return;
+
+                    const input =
+                        option.querySelector(".option-radio");
+                    if (!input || input.disabled) return;
+
+                    if (input.type === "checkbox") {
+                        input.checked = !input.checked;
+                        option.classList.toggle(
+                            "selected",
+                            input.checked
+                        );
+                        return;
+                    }
+
+                    // Uncheck all other options
+                    question
+                        .querySelectorAll(".answer-option")
+                        .forEach((opt) => {
+                            opt.classList.remove("selected");
+                            opt.querySelector(
+                                ".option-radio"
+                            ).checked = false;
+                        });
+
+                    input.checked = true;
+                    option.classList.add("selected");
+                    onComplete();
+                });
+            });
+    },
+
+    isAnswered(question) {
+        return !!question.querySelector(".option-radio:checked");
+    },
+
+    grade(question) {
+        return Array.from(
+            question.querySelectorAll(".answer-option")
+        ).every(
+            (opt) =>
+                opt.classList.contains("correct") ===
+                opt.querySelector(".option-radio").checked
+        );
+    },
+
+    lock(question) {
+        question
+            .querySelectorAll(".option-radio")
+            .forEach((opt) => {
+                opt.disabled = true;
+            });
+    },
+
+    mark(question, highlightCorrect) {
+        clearHighlights(question);
+        question
+            .querySelectorAll(".answer-option")
+            .forEach((opt) => {
+                const checked =
+                    opt.querySelector(".option-radio").checked;
+                if (
+                    opt.classList.contains("correct") &&
+                    (highlightCorrect || checked)
+                ) {
+                    opt.classList.add("highlight-correct");
+                } else if (checked) {
+                    opt.classList.add("highlight-incorrect");
+                }
+            });
+    },
+
+    reveal(question) {
+        question
+            .querySelectorAll(".answer-option")
+            .forEach((opt) => {
+                opt.querySelector(".option-radio").checked =
+                    opt.classList.contains("correct");
+            });
+    },
+
+    reset(question) {
+        question
+            .querySelectorAll(".option-radio")
+            .forEach((opt) => {
+                opt.disabled = false;
+                opt.checked = false;
+            });
+        clearHighlights(question);
+        question
+            .querySelectorAll(".answer-option")
+            .forEach((opt) => opt.classList.remove("selected"));
+    },
+
+    response(question) {
+        return Array.from(
+            question.querySelectorAll(".option-radio:checked")
+        )
+            .map((input) =>
+                input
+                    .closest(".answer-option")
+                    .querySelector(".option-text")
+                    .textContent.trim()
+            )
+            .join(", ");
+    },
+};
+
+/**
+ * Handler for typed answers, compared against the accepted answers after
+ * normalizing width, case and kana
+ */
+const textHandler = {
+    prompt: "Vui lòng nhập câu trả lời",
+
+    init(question) {
+        const input = question.querySelector(".text-answer");
+        input.addEventListener("keydown", (e) => {
+            if (
+                e.key === "Enter" &&
+                question.querySelector(".check-answer-btn")
+            ) {
+                e.preventDefault();
+                checkAnswer(question);
+            }
+        });
+    },
+
+    isAnswered(question) {
+        return (
+            question.querySelector(".text-answer").value.trim() !== ""
+        );
+    },
+
+    grade(question) {
+        const given = answerKey(
+            question.querySelector(".text-answer").value
+        );
+        return textHandler
+            .accepted(question)
+            .some((answer) => answerKey(answer) === given);
+    },
+
+    lock(question) {
+        question.querySelector(".text-answer").disabled = true;
+    },
+
+    mark(question) {
+        const input = question.querySelector(".text-answer");
+        clearHighlights(question);
+        input.classList.add(
+            textHandler.grade(question)
+                ? "highlight-correct"
+                : "highlight-incorrect"
+        );
+    },
+
+    reveal(question) {
+        question.querySelector(".text-answer").value =
+            textHandler.accepted(question)[0] || "";
+    },
+
+    reset(question) {
+        const input = question.querySelector(".text-answer");
+        input.disabled = false;
+        input.value = "";
+        clearHighlights(question);
+    },
+
+    response(question) {
+        return question.querySelector(".text-answer").value.trim();
+    },
+
+    accepted(question) {
+        return parseList(
+            question.querySelector(".text-answer-box").dataset.accepted
+        );
+    },
+};
+
+/**
+ * Handler for ordering questions: pieces are clicked into the answer row in
+ * order and clicked again to put them back
+ */
+const orderingHandler = {
+    prompt: "Vui lòng sắp xếp tất cả các phần",
+
+    init(question, onComplete) {
+        const answerRow = question.querySelector(".order-answer");
+        const pool = question.querySelector(".order-pool");
+
+        question
+            .querySelectorAll(".order-piece")
+            .forEach((piece) => {
+                piece.addEventListener("click", () => {
+                    if (piece.disabled) return;
+                    if (piece.parentElement === pool) {
+                        answerRow.appendChild(piece);
+                        if (pool.children.length === 0) onComplete();
+                    } else {
+                        orderingHandler.returnPiece(pool, piece);
+                    }
+                });
+            });
+    },
+
+    isAnswered(question) {
+        return (
+            question.querySelector(".order-pool").children.length === 0
+        );
+    },
+
+    grade(question) {
+        const expected = orderingHandler.expected(question);
+        const pieces = question.querySelectorAll(
+            ".order-answer .order-piece"
+        );
+        return (
+            pieces.length === expected.length &&
+            Array.from(pieces).every(
+                (piece, i) =>
+                    answerKey(piece.dataset.value) ===
+                    answerKey(expected[i])
+            )
+        );
+    },
+
+    lock(question) {
+        question
+            .querySelectorAll(".order-piece")
+            .forEach((piece) => {
+                piece.disabled = true;
+            });
+    },
+
+    mark(question, highlightCorrect) {
+        const expected = orderingHandler.expected(question);
+        clearHighlights(question);
+        question
+            .querySelectorAll(".order-answer .order-piece")
+            .forEach((piece, i) => {
+                const correct =
+                    answerKey(piece.dataset.value) ===
+                    answerKey(expected[i]);
+                if (correct && highlightCorrect) {
+                    piece.classList.add("highlight-correct");
+                } else if (!correct) {
+                    piece.classList.add("highlight-incorrect");
+                }
+            });
+    },
+
+    reveal(question) {
+        const answerRow = question.querySelector(".order-answer");
+        const pieces = Array.from(
+            question.querySelectorAll(".order-piece")
+        );
+        orderingHandler.expected(question).forEach((value) => {
+            const index = pieces.findIndex(
+                (piece) =>
+                    answerKey(piece.dataset.value) === answerKey(value)
+            );
+            if (index >= 0) {
+                answerRow.appendChild(pieces[index]);
+                pieces.splice(index, 1);
+            }
+        });
+    },
+
+    reset(question) {
+        const pool = question.querySelector(".order-pool");
+        clearHighlights(question);
+        question
+            .querySelectorAll(".order-piece")
+            .forEach((piece) => {
+                piece.disabled = false;
+                orderingHandler.returnPiece(pool, piece);
+            });
+    },
+
+    response(question) {
+        return Array.from(
+            question.querySelectorAll(".order-answer .option-text")
+        )
+            .map((text) => text.textContent.trim())
+            .join(" ");
+    },
+
+    expected(question) {
+        return parseList(
+            question.querySelector(".order-question").dataset.answer
+        );
+    },
+
+    // Put a piece back into the pool at its original position
+    returnPiece(pool, piece) {
+        const order = parseInt(piece.dataset.order, 10);
+        const next = Array.from(pool.children).find(
+            (other) => parseInt(other.dataset.order, 10) > order
+        );
+        pool.insertBefore(piece, next || null);
+    },
+};
+
+/**
+ * Handler for matching questions: each left item has a select with the
+ * right items and carries its correct right item in data-answer
+ */
+const matchingHandler = {
+    prompt: "Vui lòng ghép tất cả các cặp",
+
+    init(question, onComplete) {
+        question
+            .querySelectorAll(".match-select")
+            .forEach((select) => {
+                select.addEventListener("change", () => {
+                    if (matchingHandler.isAnswered(question)) {
+                        onComplete();
+                    }
+                });
+            });
+    },
+
+    isAnswered(question) {
+        return Array.from(
+            question.querySelectorAll(".match-select")
+        ).every((select) => select.value !== "");
+    },
+
+    grade(question) {
+        return Array.from(
+            question.querySelectorAll(".match-row")
+        ).every(matchingHandler.rowCorrect);
+    },
+
+    lock(question) {
+        question
+            .querySelectorAll(".match-select")
+            .forEach((select) => {
+                select.disabled = true;
+            });
+    },
+
+    mark(question, highlightCorrect) {
+        clearHighlights(question);
+        question
+            .querySelectorAll(".match-row")
+            .forEach((row) => {
+                const correct = matchingHandler.rowCorrect(row);
+                if (correct && highlightCorrect) {
+                    row.classList.add("highlight-correct");
+                } else if (!correct) {
+                    row.classList.add("highlight-incorrect");
+                }
+            });
+    },
+
+    reveal(question) {
+        question
+            .querySelectorAll(".match-row")
+            .forEach((row) => {
+                row.querySelector(".match-select").value =
+                    row.dataset.answer;
+            });
+    },
+
+    reset(question) {
+        clearHighlights(question);
+        question
+            .querySelectorAll(".match-select")
+            .forEach((select) => {
+                select.disabled = false;
+                select.value = "";
+            });
+    },
+
+    response(question) {
+        return Array.from(question.querySelectorAll(".match-row"))
+            .map(
+                (row) =>
+                    `${row
+                        .querySelector(".match-left")
+                        .textContent.trim()} → ${
+                        row.querySelector(".match-select").value
+                    }`
+            )
+            .join("; ");
+    },
+
+    rowCorrect(row) {
+        return (
+            answerKey(row.querySelector(".match-select").value) ===
+            answerKey(row.dataset.answer)
+        );
+    },
+};
+
+// Handlers by question type
+const questionTypes = {
+    single: choiceHandler,
+    multiple: Object.assign({}, choiceHandler, {
+        prompt: "Vui lòng chọn ít nhất một đáp án",
+    }),
+    text: textHandler,
+    ordering: orderingHandler,
+    matching: matchingHandler,
+};
+
+/**
+ * Read the quiz settings rendered from the content type configuration
+ * @returns {{showResultImmediately: boolean, highlightCorrect: boolean}}
+ */
+function getQuizSettings() {
+    const container = document.querySelector(
+        ".grammar-container"
+    );
+    const dataset = container ? container.dataset : {};
+    return {
+        showResultImmediately:
+            dataset.showResultImmediately === "true",
+        highlightCorrect: dataset.highlightCorrect !== "false",
+    };
+}
+
 /**
  * Check if an element is currently visible in the viewport
  * @param {HTMLElement} el - The element to check
@@ -0,0 +1,240 @@
+/**
+ * Site-wide search page functionality
+ */
+document.addEventListener("DOMContentLoaded", () => {
+    initSiteSearch();
+});
+
+// Maximum number of results rendered at once
+const MAX_RESULTS = 100;
+
+/**
+ * Initialize the search page
+ */
+function initSiteSearch() {
+    const container = document.querySelector(
+        ".search-results-container"
+    );
+    const searchInput =
+        document.getElementById("search-input");
+    const typeSelect = document.getElementById("search-type");
+
+    if (!container || !searchInput) return;
+
+    const index = new SearchIndex(
+        container.dataset.indexUrl,
+        container.dataset.baseUrl || ""
+    );
+
+    const run = debounce(async () => {
+        const query = searchInput.value;
+        const contentType = typeSelect ? typeSelect.value : "";
+
+        // Keep the query in the URL so results can be shared
+        const url = new URL(window.location.href);
+        if (query.trim()) {
+            url.searchParams.set("q", query);
+        } else {
+            url.searchParams.delete("q");
+        }
+        window.history.replaceState(null, "", url);
+
+        try {
+            const results = await index.search(
+                query,
+                contentType
+            );
+            renderResults(query, results);
+        } catch (e) {
+            setStatus("Không tải được chỉ mục tìm kiếm.");
+        }
+    }, 200);
+
+    searchInput.addEventListener("input", run);
+    if (typeSelect) typeSelect.addEventListener("change", run);
+
+    // Run the query passed in the URL, if any
+    const initial = new URLSearchParams(
+        window.location.search
+    ).get("q");
+    if (initial) {
+        searchInput.value = initial;
+        run();
+    }
+    searchInput.focus();
+}
+
+/**
+ * Lazily loaded, sharded search index
+ */
+class SearchIndex {
+    constructor(indexUrl, baseUrl) {
+        this.indexUrl = indexUrl;
+        this.baseUrl = baseUrl;
+        this.manifest = null;
+        this.shards = {};
+    }
+
+    async loadManifest() {
+        if (!this.manifest) {
+            const response = await fetch(this.indexUrl);
+            this.manifest = await response.json();
+        }
+        return this.manifest;
+    }
+
+    async loadShard(info) {
+        if (!this.shards[info.type]) {
+            const shardUrl = new URL(
+                info.file,
+                new URL(this.indexUrl, window.location.href)
+            );
+            const response = await fetch(shardUrl);
+            this.shards[info.type] = await response.json();
+        }
+        return this.shards[info.type];
+    }
+
+    async search(query, contentType) {
+        const terms = foldText(query)
+            .split(/\s+/)
+            .filter((term) => term !== "");
+        if (terms.length === 0) return [];
+
+        const manifest = await this.loadManifest();
+        const results = [];
+
+        for (const info of manifest.shards) {
+            if (contentType && info.type !== contentType) continue;
+
+            const shard = await this.loadShard(info);
+            shard.entries.forEach((entry) => {
+                if (!terms.every((term) => entry.k.includes(term)))
+                    return;
+
+                const page = shard.pages[entry.p];
+                results.push({
+                    title: entry.t,
+                    text: entry.s || "",
+                    typeTitle: shard.title,
+                    pageId: page.id,
+                    url: `${this.baseUrl}${page.url}#${entry.a}`,
+                    score: scoreEntry(entry, terms),
+                });
+            });
+        }
+
+        return results.sort((a, b) => b.score - a.score);
+    }
+}
+
+/**
+ * Rank entries whose title starts with the query first
+ * @param {Object} entry - Index entry
+ * @param {string[]} terms - Folded query terms
+ * @returns {number} - Higher is better
+ */
+function scoreEntry(entry, terms) {
+    const title = foldText(entry.t);
+    let score = 0;
+    terms.forEach((term) => {
+        if (title === term) score += 3;
+        else if (title.startsWith(term)) score += 2;
+        else if (title.includes(term)) score += 1;
+    });
+    return score;
+}
+
+/**
+ * Fold text the same way the build folds index keys: width folding,
+ * lower case, Vietnamese diacritics removed, katakana to hiragana
+ * @param {string} text - Text to fold
+ * @returns {string} - Folded text
+ */
+function foldText(text) {
+    return (text || "")
+        .normalize("NFKC")
+        .toLowerCase()
+        .normalize("NFD")
+        .replace(/[\u0300-\u036f]/g, "")
+        .normalize("NFC")
+        .replace(/đ/g, "d")
+        .replace(/[\u30a1-\u30f6]/g, (ch) =>
+            String.fromCharCode(ch.charCodeAt(0) - 0x60)
+        )
+        .trim();
+}
+
+/**
+ * Render search results
+ * @param {string} query - The raw query
+ * @param {Object[]} results - Matching entries
+ */
+function renderResults(query, results) {
+    const list = document.getElementById("search-results");
+    list.innerHTML = "";
+
+    if (query.trim() === "") {
+        setStatus("Nhập từ khóa để tìm kiếm.");
+        return;
+    }
+
+    if (results.length === 0) {
+        setStatus(`Không tìm thấy kết quả cho "${query}".`);
+        return;
+    }
+
+    setStatus(
+        results.length > MAX_RESULTS
+            ? `Hiển thị ${MAX_RESULTS} / ${results.length} kết quả.`
+            : `${results.length} kết quả.`
+    );
+
+    results.slice(0, MAX_RESULTS).forEach((result) => {
+        const item = document.createElement("li");
+        item.className = "search-result card";
+
+        const link = document.createElement("a");
+        link.href = result.url;
+        link.className = "search-result-title";
+        link.textContent = result.title;
+        item.appendChild(link);
+
+        if (result.text) {
+            const text = document.createElement("p");
+            text.className = "search-result-text";
+            text.textContent = result.text;
+            item.appendChild(text);
+        }
+
+        const meta = document.createElement("span");
+        meta.className = "search-result-meta";
+        meta.textContent = `${result.typeTitle} · ${result.pageId}`;
+        item.appendChild(meta);
+
+        list.appendChild(item);
+    });
+}
+
+/**
+ * Update the status line above the results
+ * @param {string} message - Status message
+ */
+function setStatus(message) {
+    const status = document.getElementById("search-status");
+    if (status) status.textContent = message;
+}
+
+/**
+ * Debounce function to limit how often a function is called
+ * @param {Function} func - The function to debounce
+ * @param {number} wait - Wait time in milliseconds
+ * @returns {Function} - Debounced function
+ */
+function debounce(func, wait = 300) {
+    let timeout;
+    return function executedFunction(...args) {
+        clearTimeout(timeout);
+        timeout = setTimeout(() => func(...args), wait);
+    };
+}
@@ -0,0 +1,349 @@
+/**
+ * Spaced-repetition study page functionality
+ *
+ * Cards are scheduled with the SM-2 algorithm. Review state is kept in
+ * localStorage under the stable card ID, so progress survives rebuilds and
+ * is shared between the per-file deck and the whole-type deck.
+ */
+document.addEventListener("DOMContentLoaded", () => {
+    initStudy();
+});
+
+// localStorage key prefixes
+const STATE_PREFIX = "captoc-srs:";
+const NEW_COUNT_PREFIX = "captoc-srs-new:";
+
+// Milliseconds in a day
+const DAY_MS = 24 * 60 * 60 * 1000;
+
+// Grades given by the buttons, as in SM-2 (0-5)
+const GRADE_AGAIN = 0;
+const GRADE_HARD = 3;
+const GRADE_EASY = 5;
+
+/**
+ * Load the deck and start the session
+ */
+async function initStudy() {
+    const container = document.querySelector(".study-container");
+    if (!container) return;
+
+    let deck;
+    try {
+        const response = await fetch(container.dataset.deckUrl);
+        deck = await response.json();
+
+        // Decks of password-protected files are encrypted with the password
+        // entered on the unlock page
+        if (deck.data && deck.salt) {
+            const password = sessionStorage.getItem(
+                window.captoc.passwordKey(container.dataset.lockId)
+            );
+            deck = JSON.parse(
+                await window.captoc.decryptWithPassword(deck, password || "")
+            );
+        }
+    } catch (e) {
+        showMessage("Không tải được bộ thẻ.");
+        return;
+    }
+
+    const session = new StudySession(deck);
+    session.start();
+}
+
+/**
+ * A study session over the cards due today and today's new cards
+ */
+class StudySession {
+    constructor(deck) {
+        this.deck = deck;
+        this.queue = [];
+        this.current = null;
+        this.done = 0;
+
+        this.cardEl = document.getElementById("study-card");
+        this.frontEl = this.cardEl.querySelector(".study-front");
+        this.backEl = this.cardEl.querySelector(".study-back");
+        this.showBtn = document.getElementById("study-show");
+        this.gradesEl = document.getElementById("study-grades");
+    }
+
+    start() {
+        const day = today();
+        const due = [];
+        const fresh = [];
+
+        this.deck.cards.forEach((card) => {
+            const state = loadState(card.id);
+            if (!state) {
+                fresh.push(card);
+            } else if (state.due <= day) {
+                due.push({ card, due: state.due });
+            }
+        });
+
+        // Most overdue first, then as many new cards as today allows
+        due.sort((a, b) => a.due - b.due);
+        const allowance = Math.max(
+            0,
+            this.deck.newCardsPerDay - newCardsToday(this.deck.id)
+        );
+        this.queue = due
+            .map((item) => item.card)
+            .concat(fresh.slice(0, allowance));
+
+        this.showBtn.addEventListener("click", () => this.reveal());
+        this.gradesEl
+            .querySelectorAll(".study-grade")
+            .forEach((button) => {
+                button.addEventListener("click", () => {
+                    this.grade(parseInt(button.dataset.grade, 10));
+                });
+            });
+        document.addEventListener("keydown", (e) =>
+            this.handleKey(e)
+        );
+
+        this.next();
+    }
+
+    next() {
+        this.updateStats();
+
+        if (this.queue.length === 0) {
+            this.current = null;
+            this.cardEl.classList.add("hidden");
+            this.showBtn.classList.add("hidden");
+            this.gradesEl.classList.add("hidden");
+            showMessage(
+                this.done > 0
+                    ? "Hoàn thành! Hẹn gặp lại vào ngày mai."
+                    : "Không có thẻ nào cần ôn hôm nay."
+            );
+            return;
+        }
+
+        this.current = this.queue.shift();
+        renderSide(this.frontEl, this.current.front);
+        renderSide(this.backEl, this.current.back);
+
+        this.backEl.classList.add("hidden");
+        this.cardEl.classList.remove("hidden");
+        this.showBtn.classList.remove("hidden");
+        this.gradesEl.classList.add("hidden");
+        showMessage("");
+    }
+
+    reveal() {
+        if (!this.current) return;
+        this.backEl.classList.remove("hidden");
+        this.showBtn.classList.add("hidden");
+        this.gradesEl.classList.remove("hidden");
+    }
+
+    grade(grade) {
+        if (!this.current) return;
+
+        const card = this.current;
+        const previous = loadState(card.id);
+        if (!previous) {
+            countNewCard(this.deck.id);
+        }
+        saveState(card.id, schedule(previous, grade));
+
+        if (grade === GRADE_AGAIN) {
+            // Show forgotten cards again later in the same session
+            this.queue.splice(
+                Math.min(this.queue.length, 3),
+                0,
+                card
+            );
+        } else {
+            this.done++;
+        }
+
+        this.next();
+    }
+
+    handleKey(e) {
+        if (!this.current) return;
+
+        if (
+            (e.key === " " || e.key === "Enter") &&
+            this.gradesEl.classList.contains("hidden")
+        ) {
+            e.preventDefault();
+            this.reveal();
+            return;
+        }
+
+        const grades = { 1: GRADE_AGAIN, 2: GRADE_HARD, 3: 4, 4: GRADE_EASY };
+        if (
+            e.key in grades &&
+            !this.gradesEl.classList.contains("hidden")
+        ) {
+            this.grade(grades[e.key]);
+        }
+    }
+
+    updateStats() {
+        const day = today();
+        let fresh = 0;
+        let due = 0;
+        this.queue.forEach((card) => {
+            const state = loadState(card.id);
+            if (!state) fresh++;
+            else if (state.due <= day) due++;
+        });
+        setText("study-new-count", fresh);
+        setText("study-due-count", due);
+        setText("study-done-count", this.done);
+    }
+}
+
+/**
+ * Compute the next review state with SM-2
+ * @param {Object|null} state - Current review state, null for new cards
+ * @param {number} grade - Recall quality from 0 to 5
+ * @returns {Object} - The new review state
+ */
+function schedule(state, grade) {
+    const next = Object.assign(
+        { reps: 0, interval: 0, ease: 2.5, lapses: 0 },
+        state
+    );
+    const day = today();
+
+    if (grade < GRADE_HARD) {
+        next.reps = 0;
+        next.interval = 0;
+        next.lapses++;
+    } else {
+        if (next.reps === 0) {
+            next.interval = 1;
+        } else if (next.reps === 1) {
+            next.interval = 6;
+        } else {
+            next.interval = Math.round(next.interval * next.ease);
+        }
+        if (grade === GRADE_EASY) {
+            next.interval = Math.round(next.interval * 1.3);
+        }
+        next.reps++;
+    }
+
+    next.ease = Math.max(
+        1.3,
+        next.ease + 0.1 - (5 - grade) * (0.08 + (5 - grade) * 0.02)
+    );
+    next.due = day + next.interval;
+    next.last = day;
+    return next;
+}
+
+/**
+ * Render the fields of one side of a card
+ * @param {HTMLElement} el - Container element
+ * @param {Object[]} fields - Fields with label and value
+ */
+function renderSide(el, fields) {
+    el.innerHTML = "";
+    fields.forEach((field, index) => {
+        const row = document.createElement("div");
+        row.className =
+            index === 0 ? "study-field study-field-main" : "study-field";
+
+        if (field.label && index > 0) {
+            const label = document.createElement("span");
+            label.className = "label";
+            label.textContent = `${field.label}: `;
+            row.appendChild(label);
+        }
+
+        const value = document.createElement("span");
+        value.textContent = field.value;
+        row.appendChild(value);
+
+        el.appendChild(row);
+    });
+}
+
+/**
+ * Current local day as a day number
+ * @returns {number} - Days since the epoch in local time
+ */
+function today() {
+    const now = new Date();
+    return Math.floor(
+        (now.getTime() - now.getTimezoneOffset() * 60 * 1000) / DAY_MS
+    );
+}
+
+/**
+ * Load the review state of a card
+ * @param {string} id - Stable card ID
+ * @returns {Object|null} - Review state, null if never reviewed
+ */
+function loadState(id) {
+    try {
+        return JSON.parse(localStorage.getItem(STATE_PREFIX + id));
+    } catch (e) {
+        return null;
+    }
+}
+
+/**
+ * Save the review state of a card
+ * @param {string} id - Stable card ID
+ * @param {Object} state - Review state
+ */
+function saveState(id, state) {
+    localStorage.setItem(STATE_PREFIX + id, JSON.stringify(state));
+}
+
+/**
+ * Number of new cards already introduced today from a deck
+ * @param {string} deckId - Deck ID
+ * @returns {number} - Count for today
+ */
+function newCardsToday(deckId) {
+    try {
+        const record = JSON.parse(
+            localStorage.getItem(NEW_COUNT_PREFIX + deckId)
+        );
+        return record && record.day === today() ? record.count : 0;
+    } catch (e) {
+        return 0;
+    }
+}
+
+/**
+ * Record that a new card was introduced today
+ * @param {string} deckId - Deck ID
+ */
+function countNewCard(deckId) {
+    localStorage.setItem(
+        NEW_COUNT_PREFIX + deckId,
+        JSON.stringify({ day: today(), count: newCardsToday(deckId) + 1 })
+    );
+}
+
+/**
+ * Show a status message under the card
+ * @param {string} message - Message text
+ */
+function showMessage(message) {
+    const el = document.getElementById("study-message");
+    if (el) el.textContent = message;
+}
+
+/**
+ * Set the text content of an element by ID
+ * @param {string} id - Element ID
+ * @param {*} value - Value to show
+ */
+function setText(id, value) {
+    const el = document.getElementById(id);
+    if (el) el.textContent = value;
+}
@@ -59,9 +59,9 @@ function initSearch() {
         searchInput.addEventListener(
             "input",
             debounce(() => {
-                const searchTerm = searchInput.value
-                    .toLowerCase()
-                    .trim();
+                const searchTerm = searchKey(
+                    searchInput.value
+                );
 
                 if (searchTerm === "") {
                     cards.forEach((card) => {
@@ -71,22 +71,30 @@ function initSearch() {
                     return;
                 }
 
+                // Romaji typed without spaces ("taberu") matches the
+                // romaji rendered for each card at build time
+                const romajiTerm = searchTerm.replace(/\s+/g, "");
+
                 // Filter cards
                 cards.forEach((card) => {
-                    const kanji = card
-                        .querySelector(".kanji")
-                        .textContent.toLowerCase();
-                    const reading = card
-                        .querySelector(".reading")
-                        .textContent.toLowerCase();
-                    const meaning = card
-                        .querySelector(".meaning")
-                        .textContent.toLowerCase();
+                    const kanji = searchKey(
+                        card.querySelector(".kanji").textContent
+                    );
+                    const reading = searchKey(
+                        card.querySelector(".reading").textContent
+                    );
+                    const meaning = searchKey(
+                        card.querySelector(".meaning").textContent
+                    );
+                    const romaji = (card.dataset.romaji || "")
+                        .toLowerCase()
+                        .replace(/'/g, "");
 
                     if (
                         kanji.includes(searchTerm) ||
                         reading.includes(searchTerm) ||
-                        meaning.includes(searchTerm)
+                        meaning.includes(searchTerm) ||
+                        romaji.includes(romajiTerm)
                     ) {
                         card.style.display = "block";
                         card.classList.add("animate-in");
@@ -199,6 +207,18 @@ function closeCard(card) {
     card.classList.remove("card-focus");
 }
 
+/**
+ * Normalize text for search, using the shared helper when available
+ * @param {string} text - The text to normalize
+ * @returns {string} - Normalized text
+ */
+function searchKey(text) {
+    if (window.captoc && window.captoc.normalizeText) {
+        return window.captoc.normalizeText(text);
+    }
+    return (text || "").toLowerCase().trim();
+}
+
 /**
  * Debounce function to limit how often a function is called
  * @param {Function} func - The function to debounce